@@ -7,6 +7,7 @@ import (
 
 	supa "github.com/MultiX0/solo_leveling_system/handler"
 	"github.com/MultiX0/solo_leveling_system/handler/quests"
+	"github.com/MultiX0/solo_leveling_system/storage"
 	"github.com/gorilla/mux"
 )
 
@@ -21,12 +22,14 @@ var Gray = "\033[37m"
 var White = "\033[97m"
 
 type ApiServer struct {
-	addr string
+	addr  string
+	store storage.Storage
 }
 
-func NewServer(addr string) *ApiServer {
+func NewServer(addr string, store storage.Storage) *ApiServer {
 	return &ApiServer{
-		addr: addr,
+		addr:  addr,
+		store: store,
 	}
 }
 
@@ -35,10 +38,10 @@ func (s *ApiServer) RunServer() error {
 
 	subrouter := router.PathPrefix("/api/v1").Subrouter()
 
-	supabaseHandler := supa.GetSupabaseHandler()
+	supabaseHandler := supa.GetSupabaseHandler(s.store)
 	supabaseHandler.HandleRequests(subrouter)
 
-	questsHandler := quests.GetNewQuestsHandler()
+	questsHandler := quests.GetNewQuestsHandler(s.store)
 	questsHandler.RoutesHandler(subrouter)
 
 	middlewareChain := MiddleWareChain(
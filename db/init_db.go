@@ -1,24 +1,130 @@
 package db
 
 import (
+	"fmt"
 	"log"
 	"os"
 
-	"github.com/supabase-community/supabase-go"
+	"github.com/MultiX0/solo_leveling_system/storage"
+	"github.com/MultiX0/solo_leveling_system/storage/memory"
+	"github.com/MultiX0/solo_leveling_system/storage/postgres"
+	"github.com/MultiX0/solo_leveling_system/storage/sqlite"
+	"github.com/MultiX0/solo_leveling_system/storage/supabase"
 )
 
-var SupabaseClient *supabase.Client
+// InitDB builds the storage backend named by the STORAGE env variable.
+// Supabase stays the default so existing deployments keep working.
+func InitDB() storage.Storage {
 
-func InitDB() {
+	switch os.Getenv("STORAGE") {
+	case "memory":
+		return initMemory()
+	case "postgres":
+		return initPostgres()
+	case "sqlite":
+		return initSQLite()
+	case "", "supabase":
+		return initSupabase()
+	default:
+		log.Fatalf("unknown STORAGE %q", os.Getenv("STORAGE"))
+	}
+
+	return nil
+}
 
+func initSupabase() storage.Storage {
 	_url := os.Getenv("SUPA_URL")
 	_key := os.Getenv("SUPA_KEY")
 
-	client, err := supabase.NewClient(_url, _key, &supabase.ClientOptions{})
+	store, err := supabase.New(_url, _key)
 	if err != nil {
 		log.Fatal(err)
 	}
 
-	SupabaseClient = client
+	return store
+}
+
+// Open applies pending migrations, so a fresh database is ready to use
+// once this returns.
+func initPostgres() storage.Storage {
+	store, err := postgres.Open(os.Getenv("DATABASE_URL"))
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	return store
+}
+
+func sqlitePath() string {
+	if path := os.Getenv("SQLITE_PATH"); path != "" {
+		return path
+	}
+
+	return "solo_leveling.db"
+}
+
+// The catalogue is seeded on first start so a fresh file is playable
+// without calling /init.
+func initSQLite() storage.Storage {
+	store, err := sqlite.Open(sqlitePath())
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if err = seed(store); err != nil {
+		log.Fatal(err)
+	}
+
+	return store
+}
+
+// Migrate applies the pending migrations of the configured backend
+// without starting the server and returns the schema version.
+func Migrate() (int, error) {
+	switch os.Getenv("STORAGE") {
+	case "postgres":
+		conn, err := postgres.Connect(os.Getenv("DATABASE_URL"))
+		if err != nil {
+			return 0, err
+		}
+		defer conn.Close()
+
+		return postgres.Migrate(conn)
+	case "sqlite":
+		conn, err := sqlite.Connect(sqlitePath())
+		if err != nil {
+			return 0, err
+		}
+		defer conn.Close()
+
+		return sqlite.Migrate(conn)
+	}
+
+	return 0, fmt.Errorf("STORAGE=%q has no migrations to run", os.Getenv("STORAGE"))
+}
+
+// The memory backend starts empty on every run, so seed it with the
+// same catalogue /init would insert.
+func initMemory() storage.Storage {
+	store := memory.New()
+
+	if err := seed(store); err != nil {
+		log.Fatal(err)
+	}
+
+	return store
+}
+
+func seed(store storage.Storage) error {
+	quests, err := storage.ReadQuests("quests.json")
+	if err != nil {
+		return err
+	}
+
+	skills, err := storage.ReadSkills("skills.json")
+	if err != nil {
+		return err
+	}
 
+	return storage.Seed(store, quests, skills)
 }
@@ -0,0 +1,142 @@
+package functions
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/MultiX0/solo_leveling_system/storage"
+	"github.com/MultiX0/solo_leveling_system/types"
+)
+
+var (
+	// WeeklyQuestSlots and MonthlyQuestSlots are how many weekly and
+	// monthly quests a player gets per week and month.
+	WeeklyQuestSlots  = 1
+	MonthlyQuestSlots = 1
+	// WeeklyMultiplier and MonthlyMultiplier scale the XP a weekly or
+	// monthly quest gives, and takes when it expires, compared to a daily
+	// quest of the same priority.
+	WeeklyMultiplier  = 5
+	MonthlyMultiplier = 15
+)
+
+func isDaily(cadence string) bool {
+	return cadence == "" || cadence == types.CadenceDaily
+}
+
+// isMainQuest tells whether the quest is a daily main quest.
+func isMainQuest(quest *types.Quest) bool {
+	return quest.Priority == 1 && isDaily(quest.Cadence)
+}
+
+// isSideQuest tells whether the quest is a daily side quest.
+func isSideQuest(quest *types.Quest) bool {
+	return quest.Priority > 1 && isDaily(quest.Cadence)
+}
+
+func cadenceMultiplier(cadence string) int {
+	switch cadence {
+	case types.CadenceWeekly:
+		return WeeklyMultiplier
+	case types.CadenceMonthly:
+		return MonthlyMultiplier
+	}
+	return 1
+}
+
+func cadenceKind(cadence string) storage.QuestKind {
+	if cadence == types.CadenceWeekly {
+		return storage.WeeklyQuests
+	}
+	return storage.MonthlyQuests
+}
+
+func cadenceSlots(cadence string) int {
+	if cadence == types.CadenceWeekly {
+		return WeeklyQuestSlots
+	}
+	return MonthlyQuestSlots
+}
+
+// GetPeriodQuests returns the player's active weekly or monthly quests
+// and the earliest of their deadlines, handing out new ones when none were
+// given this week or month yet. No quests are returned once this period's
+// quests are finished or expired.
+func GetPeriodQuests(store storage.Storage, playerId string, cadence string) ([]*types.Quest, error, time.Time) {
+	if cadence != types.CadenceWeekly && cadence != types.CadenceMonthly {
+		return nil, fmt.Errorf("unknown quest cadence %q", cadence), time.Time{}
+	}
+
+	id, err := strconv.Atoi(playerId)
+	if err != nil {
+		return nil, err, time.Time{}
+	}
+
+	player, err := store.GetPlayer(id)
+	if err != nil {
+		return nil, err, time.Time{}
+	}
+
+	periodStart, _ := periodBounds(player, cadence, clock())
+	given, err := store.ListPlayerQuests(storage.PlayerQuestFilter{
+		PlayerID: id,
+		Kind:     cadenceKind(cadence),
+	})
+	if err != nil {
+		return nil, err, time.Time{}
+	}
+
+	quests := []*types.Quest{}
+	var deadline time.Time
+	givenThisPeriod := false
+	for _, pq := range given {
+		if !pq.StartAt.Before(periodStart) {
+			givenThisPeriod = true
+		}
+		if pq.Status != types.QuestActive {
+			continue
+		}
+
+		quest, err := getQuestByID(store, strconv.Itoa(pq.QuestID))
+		if err != nil {
+			return nil, err, time.Time{}
+		}
+		quests = append(quests, quest)
+		if deadline.IsZero() || pq.Deadline.Before(deadline) {
+			deadline = pq.Deadline
+		}
+	}
+
+	if len(quests) > 0 || givenThisPeriod {
+		return quests, nil, deadline
+	}
+
+	slots := min(cadenceSlots(cadence), questPoolSize(store, cadenceKind(cadence), id))
+	if slots == 0 {
+		return quests, nil, deadline
+	}
+
+	effects, err := PassiveEffects(store, id)
+	if err != nil {
+		return nil, err, time.Time{}
+	}
+
+	for len(quests) < slots {
+		quest, err := fetchQuest(store, cadenceKind(cadence), id, quests...)
+		if err != nil {
+			return nil, err, time.Time{}
+		}
+
+		pq, err := insertQuestToPlayerQuests(store, quest, id, effects)
+		if err != nil {
+			return nil, err, time.Time{}
+		}
+		quests = append(quests, quest)
+		if deadline.IsZero() || pq.Deadline.Before(deadline) {
+			deadline = pq.Deadline
+		}
+	}
+
+	return quests, nil, deadline
+}
@@ -0,0 +1,241 @@
+package functions
+
+import (
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/MultiX0/solo_leveling_system/types"
+)
+
+func TestPeriodBounds(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tokyo, err := time.LoadLocation("Asia/Tokyo")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	utc := types.Schedule{TimeZone: "UTC"}
+	// days start at 06:00 in Tokyo, 21:00 UTC the day before
+	tokyoMornings := types.Schedule{TimeZone: "Asia/Tokyo", ResetHour: 6}
+
+	tests := []struct {
+		name     string
+		schedule types.Schedule
+		cadence  string
+		at       time.Time
+		start    time.Time
+		end      time.Time
+	}{
+		{
+			name:     "midweek",
+			schedule: utc,
+			cadence:  types.CadenceWeekly,
+			at:       time.Date(2026, 10, 14, 12, 0, 0, 0, time.UTC),
+			start:    time.Date(2026, 10, 12, 0, 0, 0, 0, time.UTC),
+			end:      time.Date(2026, 10, 19, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name:     "the last minute of a week",
+			schedule: utc,
+			cadence:  types.CadenceWeekly,
+			at:       time.Date(2026, 10, 18, 23, 59, 0, 0, time.UTC),
+			start:    time.Date(2026, 10, 12, 0, 0, 0, 0, time.UTC),
+			end:      time.Date(2026, 10, 19, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name:     "monday at the reset",
+			schedule: utc,
+			cadence:  types.CadenceWeekly,
+			at:       time.Date(2026, 10, 19, 0, 0, 0, 0, time.UTC),
+			start:    time.Date(2026, 10, 19, 0, 0, 0, 0, time.UTC),
+			end:      time.Date(2026, 10, 26, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name:     "a week across two months",
+			schedule: utc,
+			cadence:  types.CadenceWeekly,
+			at:       time.Date(2026, 11, 1, 8, 0, 0, 0, time.UTC),
+			start:    time.Date(2026, 10, 26, 0, 0, 0, 0, time.UTC),
+			end:      time.Date(2026, 11, 2, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name:     "a week across two years",
+			schedule: utc,
+			cadence:  types.CadenceWeekly,
+			at:       time.Date(2027, 1, 1, 8, 0, 0, 0, time.UTC),
+			start:    time.Date(2026, 12, 28, 0, 0, 0, 0, time.UTC),
+			end:      time.Date(2027, 1, 4, 0, 0, 0, 0, time.UTC),
+		},
+		// sunday 22:00 UTC is already monday morning in Tokyo
+		{
+			name:     "monday in the player's time zone",
+			schedule: tokyoMornings,
+			cadence:  types.CadenceWeekly,
+			at:       time.Date(2026, 10, 18, 22, 0, 0, 0, time.UTC),
+			start:    time.Date(2026, 10, 19, 6, 0, 0, 0, tokyo),
+			end:      time.Date(2026, 10, 26, 6, 0, 0, 0, tokyo),
+		},
+		// monday 05:00 in Tokyo still belongs to the week before
+		{
+			name:     "monday before the reset hour",
+			schedule: tokyoMornings,
+			cadence:  types.CadenceWeekly,
+			at:       time.Date(2026, 10, 18, 20, 0, 0, 0, time.UTC),
+			start:    time.Date(2026, 10, 12, 6, 0, 0, 0, tokyo),
+			end:      time.Date(2026, 10, 19, 6, 0, 0, 0, tokyo),
+		},
+		// the clocks go back on sunday the 25th, the week is an hour longer
+		{
+			name:     "a week across a daylight saving change",
+			schedule: types.Schedule{TimeZone: "Europe/Berlin"},
+			cadence:  types.CadenceWeekly,
+			at:       time.Date(2026, 10, 25, 12, 0, 0, 0, time.UTC),
+			start:    time.Date(2026, 10, 18, 22, 0, 0, 0, time.UTC),
+			end:      time.Date(2026, 10, 25, 23, 0, 0, 0, time.UTC),
+		},
+		{
+			name:     "midmonth",
+			schedule: utc,
+			cadence:  types.CadenceMonthly,
+			at:       time.Date(2026, 10, 17, 12, 0, 0, 0, time.UTC),
+			start:    time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC),
+			end:      time.Date(2026, 11, 1, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name:     "the last minute of a month",
+			schedule: utc,
+			cadence:  types.CadenceMonthly,
+			at:       time.Date(2026, 10, 31, 23, 59, 0, 0, time.UTC),
+			start:    time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC),
+			end:      time.Date(2026, 11, 1, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name:     "december",
+			schedule: utc,
+			cadence:  types.CadenceMonthly,
+			at:       time.Date(2026, 12, 31, 12, 0, 0, 0, time.UTC),
+			start:    time.Date(2026, 12, 1, 0, 0, 0, 0, time.UTC),
+			end:      time.Date(2027, 1, 1, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name:     "february",
+			schedule: utc,
+			cadence:  types.CadenceMonthly,
+			at:       time.Date(2028, 2, 29, 12, 0, 0, 0, time.UTC),
+			start:    time.Date(2028, 2, 1, 0, 0, 0, 0, time.UTC),
+			end:      time.Date(2028, 3, 1, 0, 0, 0, 0, time.UTC),
+		},
+		// 22:00 UTC on the 31st is the 1st in Tokyo
+		{
+			name:     "the 1st in the player's time zone",
+			schedule: tokyoMornings,
+			cadence:  types.CadenceMonthly,
+			at:       time.Date(2026, 10, 31, 22, 0, 0, 0, time.UTC),
+			start:    time.Date(2026, 11, 1, 6, 0, 0, 0, tokyo),
+			end:      time.Date(2026, 12, 1, 6, 0, 0, 0, tokyo),
+		},
+		{
+			name:     "the 1st before the reset hour",
+			schedule: tokyoMornings,
+			cadence:  types.CadenceMonthly,
+			at:       time.Date(2026, 10, 31, 20, 0, 0, 0, time.UTC),
+			start:    time.Date(2026, 10, 1, 6, 0, 0, 0, tokyo),
+			end:      time.Date(2026, 11, 1, 6, 0, 0, 0, tokyo),
+		},
+		{
+			name:     "a month across a daylight saving change",
+			schedule: types.Schedule{TimeZone: "Europe/Berlin"},
+			cadence:  types.CadenceMonthly,
+			at:       time.Date(2026, 10, 31, 12, 0, 0, 0, time.UTC),
+			start:    time.Date(2026, 10, 1, 0, 0, 0, 0, berlin),
+			end:      time.Date(2026, 11, 1, 0, 0, 0, 0, berlin),
+		},
+	}
+
+	for _, test := range tests {
+		player := &types.Player{Schedule: test.schedule}
+		start, end := periodBounds(player, test.cadence, test.at)
+		if !start.Equal(test.start) || !end.Equal(test.end) {
+			t.Errorf("%s: periodBounds(%v) = %v, %v, want %v, %v", test.name, test.at, start, end, test.start, test.end)
+		}
+	}
+}
+
+func TestGetPeriodQuests(t *testing.T) {
+	// a wednesday
+	start := time.Date(2026, 10, 14, 12, 0, 0, 0, time.UTC)
+	store, player := newTestStore(t, start)
+	id := strconv.Itoa(player.ID)
+
+	tests := []struct {
+		cadence string
+		// a moment in the same period, and one in the next
+		later    time.Time
+		next     time.Time
+		deadline time.Time
+	}{
+		{
+			cadence:  types.CadenceWeekly,
+			later:    time.Date(2026, 10, 18, 23, 59, 0, 0, time.UTC),
+			next:     time.Date(2026, 10, 19, 0, 0, 0, 0, time.UTC),
+			deadline: time.Date(2026, 10, 19, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			cadence:  types.CadenceMonthly,
+			later:    time.Date(2026, 10, 31, 23, 59, 0, 0, time.UTC),
+			next:     time.Date(2026, 11, 1, 0, 0, 0, 0, time.UTC),
+			deadline: time.Date(2026, 11, 1, 0, 0, 0, 0, time.UTC),
+		},
+	}
+
+	for _, test := range tests {
+		clock = func() time.Time { return start }
+		quests, err, deadline := GetPeriodQuests(store, id, test.cadence)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(quests) != 1 || quests[0].Cadence != test.cadence {
+			t.Fatalf("%s: got %v, want one %s quest", test.cadence, quests, test.cadence)
+		}
+		if !deadline.Equal(test.deadline) {
+			t.Errorf("%s: deadline %v, want %v", test.cadence, deadline, test.deadline)
+		}
+
+		if _, err = FinishQuest(store, id, strconv.Itoa(quests[0].ID)); err != nil {
+			t.Fatal(err)
+		}
+
+		clock = func() time.Time { return test.later }
+		if again, err, _ := GetPeriodQuests(store, id, test.cadence); err != nil || len(again) != 0 {
+			t.Errorf("%s: after finishing it in the same period got %v (%v), want none", test.cadence, again, err)
+		}
+
+		clock = func() time.Time { return test.next }
+		if next, err, _ := GetPeriodQuests(store, id, test.cadence); err != nil || len(next) != 1 {
+			t.Errorf("%s: in the next period got %v (%v), want one quest", test.cadence, next, err)
+		}
+	}
+}
+
+func TestCadenceXP(t *testing.T) {
+	tests := []struct {
+		cadence string
+		want    int
+	}{
+		{"", QuestXP * priorityWeight(3)},
+		{types.CadenceDaily, QuestXP * priorityWeight(3)},
+		{types.CadenceWeekly, WeeklyMultiplier * QuestXP * priorityWeight(3)},
+		{types.CadenceMonthly, MonthlyMultiplier * QuestXP * priorityWeight(3)},
+	}
+
+	for _, test := range tests {
+		quest := &types.Quest{Priority: 3, Cadence: test.cadence}
+		if got := QuestXPReward(quest); got != test.want {
+			t.Errorf("%q quest: %d xp, want %d", test.cadence, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,135 @@
+package functions
+
+import (
+	"sort"
+	"strconv"
+
+	"github.com/MultiX0/solo_leveling_system/storage"
+	"github.com/MultiX0/solo_leveling_system/types"
+)
+
+// ChainProgress is how far a player got in a quest chain.
+type ChainProgress struct {
+	Chain string `json:"chain"`
+	// Step is the last step the player completed, out of Steps.
+	Step      int          `json:"step"`
+	Steps     int          `json:"steps"`
+	Completed bool         `json:"completed"`
+	Next      *types.Quest `json:"next,omitempty"`
+}
+
+// questChains are the steps of every chain in quests.json, in order.
+func questChains(store storage.Storage) map[string][]types.Quest {
+	lazyInitQuestPool(store, storage.SideQuests)
+
+	poolCacheMux.RLock()
+	defer poolCacheMux.RUnlock()
+
+	chains := map[string][]types.Quest{}
+	for _, quest := range questPoolCache[storage.SideQuests] {
+		if quest.Chain != "" {
+			chains[quest.Chain] = append(chains[quest.Chain], quest)
+		}
+	}
+
+	for _, steps := range chains {
+		sort.Slice(steps, func(i, j int) bool { return steps[i].ChainStep < steps[j].ChainStep })
+	}
+
+	return chains
+}
+
+// nextChainStep is the first step of the chain after step, nil when the
+// chain is complete.
+func nextChainStep(steps []types.Quest, step int) *types.Quest {
+	for i := range steps {
+		if steps[i].ChainStep > step {
+			return &steps[i]
+		}
+	}
+	return nil
+}
+
+func newChainProgress(chain string, steps []types.Quest, step int) *ChainProgress {
+	next := nextChainStep(steps, step)
+	return &ChainProgress{
+		Chain:     chain,
+		Step:      step,
+		Steps:     len(steps),
+		Completed: next == nil,
+		Next:      next,
+	}
+}
+
+// nextChainQuests are the quests that continue the chains the player
+// started, which GetSideQuests hands out before any random pick.
+func nextChainQuests(store storage.Storage, playerId int) ([]*types.Quest, error) {
+	started, err := store.ListPlayerChains(playerId)
+	if err != nil {
+		return nil, err
+	}
+
+	chains := questChains(store)
+
+	var next []*types.Quest
+	for _, pc := range started {
+		if quest := nextChainStep(chains[pc.Chain], pc.Step); quest != nil {
+			next = append(next, quest)
+		}
+	}
+
+	return next, nil
+}
+
+// advanceChain records the chain step the player just completed and
+// returns how far they got, which an earlier step done again doesn't set
+// back. It is meant to run inside Atomic.
+func advanceChain(tx storage.Storage, playerId int, quest *types.Quest) (*ChainProgress, error) {
+	if quest.Chain == "" {
+		return nil, nil
+	}
+
+	if err := tx.AdvancePlayerChain(playerId, quest.Chain, quest.ChainStep); err != nil {
+		return nil, err
+	}
+
+	started, err := tx.ListPlayerChains(playerId)
+	if err != nil {
+		return nil, err
+	}
+
+	step := quest.ChainStep
+	for _, pc := range started {
+		if pc.Chain == quest.Chain {
+			step = pc.Step
+		}
+	}
+
+	return newChainProgress(quest.Chain, questChains(tx)[quest.Chain], step), nil
+}
+
+// GetChains returns how far the player got in every chain they started.
+func GetChains(store storage.Storage, playerId string) ([]*ChainProgress, error) {
+	id, err := strconv.Atoi(playerId)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err = store.GetPlayer(id); err != nil {
+		return nil, err
+	}
+
+	started, err := store.ListPlayerChains(id)
+	if err != nil {
+		return nil, err
+	}
+
+	chains := questChains(store)
+
+	progress := []*ChainProgress{}
+	for _, pc := range started {
+		progress = append(progress, newChainProgress(pc.Chain, chains[pc.Chain], pc.Step))
+	}
+
+	return progress, nil
+}
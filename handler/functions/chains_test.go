@@ -0,0 +1,110 @@
+package functions
+
+import (
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/MultiX0/solo_leveling_system/storage"
+	"github.com/MultiX0/solo_leveling_system/types"
+)
+
+func TestQuestChains(t *testing.T) {
+	start := time.Date(2026, 10, 12, 10, 0, 0, 0, time.UTC)
+	store, player := newTestStore(t, start)
+	id := strconv.Itoa(player.ID)
+
+	wolfHunt := questChains(store)["Wolf Hunt"]
+	if len(wolfHunt) != 3 {
+		t.Fatalf("Wolf Hunt has %d steps, want 3", len(wolfHunt))
+	}
+	for i, step := range wolfHunt {
+		if step.ChainStep != i+1 {
+			t.Fatalf("step %d of Wolf Hunt is chain step %d", i+1, step.ChainStep)
+		}
+	}
+
+	pool, err := questPool(store, storage.SideQuests, player.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, quest := range pool {
+		if quest.ChainStep > 1 {
+			t.Errorf("%q, step %d of %s, is in the random pool", quest.Title, quest.ChainStep, quest.Chain)
+		}
+	}
+
+	// each step is finished on its own day
+	tests := []struct {
+		name   string
+		finish int
+		step   int
+		next   string
+	}{
+		{"the first step", 0, 1, wolfHunt[1].Title},
+		{"the first step again", 0, 1, wolfHunt[1].Title},
+		{"the second step", 1, 2, wolfHunt[2].Title},
+		{"the first step after the second", 0, 2, wolfHunt[2].Title},
+		{"the last step", 2, 3, ""},
+	}
+
+	for day, test := range tests {
+		clock = func() time.Time { return start.Add(time.Duration(day) * 24 * time.Hour) }
+
+		quest := &wolfHunt[test.finish]
+		if _, err := insertQuestToPlayerQuests(store, quest, player.ID, types.SkillEffects{}); err != nil {
+			t.Fatal(err)
+		}
+		reward, err := FinishQuest(store, id, strconv.Itoa(quest.ID))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		want := ChainProgress{Chain: "Wolf Hunt", Step: test.step, Steps: 3, Completed: test.next == ""}
+		got := *reward.Chain
+		next := ""
+		if got.Next != nil {
+			next = got.Next.Title
+		}
+		got.Next = nil
+		if got != want || next != test.next {
+			t.Errorf("%s: progress %+v next %q, want %+v next %q", test.name, got, next, want, test.next)
+		}
+
+		chains, err := GetChains(store, id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(chains) != 1 || chains[0].Step != test.step {
+			t.Errorf("%s: GetChains = %+v, want Wolf Hunt at step %d", test.name, chains, test.step)
+		}
+
+		// the next step goes before any random pick
+		continued, err := nextChainQuests(store, player.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		switch {
+		case test.next == "" && len(continued) != 0:
+			t.Errorf("%s: the completed chain continues with %q", test.name, continued[0].Title)
+		case test.next != "" && (len(continued) != 1 || continued[0].Title != test.next):
+			t.Errorf("%s: continues with %v, want %q", test.name, continued, test.next)
+		}
+	}
+}
+
+func TestNextChainStepGoesFirst(t *testing.T) {
+	store, player := newTestStore(t, time.Date(2026, 10, 12, 10, 0, 0, 0, time.UTC))
+	wolfHunt := questChains(store)["Wolf Hunt"]
+
+	if err := store.AdvancePlayerChain(player.ID, "Wolf Hunt", 2); err != nil {
+		t.Fatal(err)
+	}
+	sideQuests, err, _ := GetSideQuests(store, strconv.Itoa(player.ID))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sideQuests) == 0 || sideQuests[0].Title != wolfHunt[2].Title {
+		t.Errorf("side quests %v, want %q first", sideQuests, wolfHunt[2].Title)
+	}
+}
@@ -0,0 +1,206 @@
+package functions
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/MultiX0/solo_leveling_system/storage"
+	"github.com/MultiX0/solo_leveling_system/types"
+)
+
+var (
+	ErrInvalidCustomQuest  = errors.New("a quest needs a title, a priority from 1 to 5 and a daily, weekly or monthly cadence")
+	ErrCustomQuestNotFound = errors.New("you have no such quest")
+	ErrTooManyCustomQuests = errors.New("you have too many quests of your own, delete one first")
+	ErrQuestTitleTaken     = errors.New("there already is a quest with this title, pick another one")
+)
+
+// MaxCustomQuests is how many quests of their own a player can have.
+var MaxCustomQuests = 20
+
+// MaxCustomQuestPriority keeps custom quests within the priorities the
+// quests everyone gets use.
+const MaxCustomQuestPriority = MaxQuestPriority
+
+func validateCustomQuest(quest *types.Quest) error {
+	quest.Title = strings.TrimSpace(quest.Title)
+	if quest.Title == "" || quest.Priority < 1 || quest.Priority > MaxCustomQuestPriority {
+		return ErrInvalidCustomQuest
+	}
+
+	// chains, triggers and weights only come from quests.json
+	quest.Chain, quest.ChainStep = "", 0
+	quest.Trigger = nil
+	quest.Weight = 0
+	quest.Category = strings.TrimSpace(quest.Category)
+
+	if quest.Cadence == "" {
+		quest.Cadence = types.CadenceDaily
+	}
+	if quest.Cadence != types.CadenceDaily && quest.Cadence != types.CadenceWeekly && quest.Cadence != types.CadenceMonthly {
+		return ErrInvalidCustomQuest
+	}
+
+	names := map[string]bool{}
+	for _, objective := range quest.Objectives {
+		name := strings.ToLower(strings.TrimSpace(objective.Name))
+		if name == "" || names[name] || objective.Target <= 0 || objective.PerLevel < 0 || objective.Max < 0 {
+			return fmt.Errorf("%w, objectives need a unique name and a positive target", ErrInvalidCustomQuest)
+		}
+		names[name] = true
+	}
+
+	return nil
+}
+
+// checkCustomQuestTitle keeps the player's quest from taking the title of
+// a quest everyone gets or of another one of their own, whatever the case,
+// so quests handed out together never share a title.
+func checkCustomQuestTitle(store storage.Storage, playerId int, quest *types.Quest) error {
+	global, err := store.ListQuests(storage.AnyQuest)
+	if err != nil {
+		return err
+	}
+
+	owned, err := store.ListCustomQuests(playerId, storage.AnyQuest)
+	if err != nil {
+		return err
+	}
+
+	for _, other := range append(global, owned...) {
+		if other.ID != quest.ID && strings.EqualFold(other.Title, quest.Title) {
+			return ErrQuestTitleTaken
+		}
+	}
+
+	return nil
+}
+
+// ListCustomQuests lists the quests the player made for themselves.
+func ListCustomQuests(store storage.Storage, playerId string) ([]types.Quest, error) {
+	id, err := strconv.Atoi(playerId)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err = store.GetPlayer(id); err != nil {
+		return nil, err
+	}
+
+	quests, err := store.ListCustomQuests(id, storage.AnyQuest)
+	if err != nil {
+		return nil, err
+	}
+
+	if quests == nil {
+		quests = []types.Quest{}
+	}
+
+	return quests, nil
+}
+
+// CreateCustomQuest adds a quest to the player's own pool. Nobody else
+// ever gets it.
+func CreateCustomQuest(store storage.Storage, playerId string, quest *types.Quest) (*types.Quest, error) {
+	id, err := strconv.Atoi(playerId)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = validateCustomQuest(quest); err != nil {
+		return nil, err
+	}
+
+	if _, err = store.GetPlayer(id); err != nil {
+		return nil, err
+	}
+
+	owned, err := store.ListCustomQuests(id, storage.AnyQuest)
+	if err != nil {
+		return nil, err
+	}
+	if len(owned) >= MaxCustomQuests {
+		return nil, ErrTooManyCustomQuests
+	}
+
+	quest.ID = 0
+	if err = checkCustomQuestTitle(store, id, quest); err != nil {
+		return nil, err
+	}
+
+	quest.Owner = id
+	return store.CreateQuest(quest)
+}
+
+// ownCustomQuest is the player's quest with the given ID.
+func ownCustomQuest(store storage.Storage, playerId, questId string) (*types.Quest, error) {
+	pId, err := strconv.Atoi(playerId)
+	if err != nil {
+		return nil, err
+	}
+
+	qId, err := strconv.Atoi(questId)
+	if err != nil {
+		return nil, err
+	}
+
+	quest, err := store.GetQuest(qId)
+	if errors.Is(err, storage.ErrNotFound) {
+		return nil, ErrCustomQuestNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if quest.Owner != pId || quest.Archived {
+		return nil, ErrCustomQuestNotFound
+	}
+
+	return quest, nil
+}
+
+// UpdateCustomQuest changes one of the player's own quests. Quests that
+// were already given keep what they asked for when they were given.
+func UpdateCustomQuest(store storage.Storage, playerId, questId string, quest *types.Quest) (*types.Quest, error) {
+	existing, err := ownCustomQuest(store, playerId, questId)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = validateCustomQuest(quest); err != nil {
+		return nil, err
+	}
+
+	quest.ID = existing.ID
+	if err = checkCustomQuestTitle(store, existing.Owner, quest); err != nil {
+		return nil, err
+	}
+
+	quest.Owner = existing.Owner
+	quest.Archived = false
+	if err = store.UpdateQuest(quest); err != nil {
+		return nil, err
+	}
+	forgetQuest(quest.ID)
+
+	return quest, nil
+}
+
+// DeleteCustomQuest takes one of the player's own quests out of their
+// pool. It is only archived, so the quests already given keep their
+// history.
+func DeleteCustomQuest(store storage.Storage, playerId, questId string) error {
+	quest, err := ownCustomQuest(store, playerId, questId)
+	if err != nil {
+		return err
+	}
+
+	if err = store.ArchiveQuest(quest.ID); err != nil {
+		return err
+	}
+	forgetQuest(quest.ID)
+
+	return nil
+}
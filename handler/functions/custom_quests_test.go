@@ -0,0 +1,42 @@
+package functions
+
+import (
+	"errors"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/MultiX0/solo_leveling_system/types"
+)
+
+func TestCustomQuestTitlesClashWhateverTheCase(t *testing.T) {
+	store, player := newTestStore(t, time.Date(2026, 10, 12, 10, 0, 0, 0, time.UTC))
+	id := strconv.Itoa(player.ID)
+
+	custom := func(title string) *types.Quest {
+		return &types.Quest{Title: title, Priority: 2, Cadence: types.CadenceDaily}
+	}
+
+	study, err := CreateCustomQuest(store, id, custom("Study Go"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	read, err := CreateCustomQuest(store, id, custom("Read a Book"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, title := range []string{"morning workout", "MORNING WORKOUT", "study go", "Study GO"} {
+		if _, err := CreateCustomQuest(store, id, custom(title)); !errors.Is(err, ErrQuestTitleTaken) {
+			t.Errorf("creating %q: %v, want %v", title, err, ErrQuestTitleTaken)
+		}
+		if _, err := UpdateCustomQuest(store, id, strconv.Itoa(read.ID), custom(title)); !errors.Is(err, ErrQuestTitleTaken) {
+			t.Errorf("renaming a quest to %q: %v, want %v", title, err, ErrQuestTitleTaken)
+		}
+	}
+
+	// a quest keeps its own title in any case
+	if _, err := UpdateCustomQuest(store, id, strconv.Itoa(study.ID), custom("STUDY GO")); err != nil {
+		t.Errorf("changing the case of a quest's own title: %v", err)
+	}
+}
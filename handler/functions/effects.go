@@ -0,0 +1,94 @@
+package functions
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/MultiX0/solo_leveling_system/storage"
+	"github.com/MultiX0/solo_leveling_system/types"
+)
+
+// SideQuestSlots is how many side quests a player gets per day before
+// passive skills add more.
+var SideQuestSlots = 2
+
+// PassiveEffects adds up the effects of every passive skill the player owns.
+func PassiveEffects(store storage.Storage, playerId int) (types.SkillEffects, error) {
+	var effects types.SkillEffects
+
+	owned, err := store.ListPlayerSkills(playerId)
+	if err != nil {
+		return effects, err
+	}
+
+	for _, ps := range owned {
+		skill, err := store.GetSkill(ps.SkillID)
+		if err != nil {
+			return effects, err
+		}
+		if skill.Type == types.SkillPassive && skill.Effects != nil {
+			effects = effects.Add(*skill.Effects)
+		}
+	}
+
+	return effects, nil
+}
+
+func withPercent(value int, percent int) int {
+	return value + value*percent/100
+}
+
+// QuestXPWithEffects is QuestXPReward plus the bonus for the quest's kind.
+// Only daily main and side quests have one.
+func QuestXPWithEffects(quest *types.Quest, effects types.SkillEffects) int {
+	switch {
+	case isMainQuest(quest):
+		return withPercent(QuestXPReward(quest), effects.MainQuestXP)
+	case isSideQuest(quest):
+		return withPercent(QuestXPReward(quest), effects.SideQuestXP)
+	}
+	return QuestXPReward(quest)
+}
+
+// PunishmentWithEffects is PunishmentXP after reductions, which can't take
+// it below zero.
+func PunishmentWithEffects(effects types.SkillEffects) int {
+	return withPercent(PunishmentXP, -min(effects.PunishmentReduction, 100))
+}
+
+// cadencePunishment is what expiring a quest of the given cadence costs.
+func cadencePunishment(cadence string, effects types.SkillEffects) int {
+	return PunishmentWithEffects(effects) * cadenceMultiplier(cadence)
+}
+
+func SideQuestCount(effects types.SkillEffects) int {
+	return SideQuestSlots + max(effects.ExtraSideQuests, 0)
+}
+
+// QuestDeadline is when a quest given to the player at start expires: at
+// the end of their day, week or month depending on the quest's cadence,
+// plus what passive skills add. Event quests are due their trigger's hours
+// after they were given.
+func QuestDeadline(player *types.Player, quest *types.Quest, start time.Time, effects types.SkillEffects) time.Time {
+	if quest.Trigger != nil {
+		return start.Add(time.Duration(quest.Trigger.Hours) * time.Hour)
+	}
+
+	_, end := periodBounds(player, quest.Cadence, start)
+	return end.Add(time.Duration(max(effects.DeadlineHours, 0)) * time.Hour)
+}
+
+// PlayerPunishment is how much XP the player loses for every expired quest.
+func PlayerPunishment(store storage.Storage, playerId string) (int, error) {
+	id, err := strconv.Atoi(playerId)
+	if err != nil {
+		return 0, err
+	}
+
+	effects, err := PassiveEffects(store, id)
+	if err != nil {
+		return 0, err
+	}
+
+	return PunishmentWithEffects(effects), nil
+}
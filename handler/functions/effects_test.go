@@ -0,0 +1,36 @@
+package functions
+
+import (
+	"testing"
+
+	"github.com/MultiX0/solo_leveling_system/types"
+)
+
+func TestSideQuestXPOnlyBoostsDailySideQuests(t *testing.T) {
+	effects := types.SkillEffects{SideQuestXP: 50}
+	trigger := &types.QuestTrigger{Type: types.TriggerEmergency, Hours: 4}
+
+	tests := []struct {
+		name    string
+		quest   *types.Quest
+		boosted bool
+	}{
+		{"daily side quest", &types.Quest{Priority: 3, Cadence: types.CadenceDaily}, true},
+		{"side quest without a cadence", &types.Quest{Priority: 3}, true},
+		{"daily main quest", &types.Quest{Priority: 1, Cadence: types.CadenceDaily}, false},
+		{"weekly quest", &types.Quest{Priority: 3, Cadence: types.CadenceWeekly}, false},
+		{"monthly quest", &types.Quest{Priority: 3, Cadence: types.CadenceMonthly}, false},
+		{"event quest", &types.Quest{Priority: 3, Cadence: types.CadenceEvent, Trigger: trigger}, false},
+	}
+
+	for _, test := range tests {
+		base := QuestXPReward(test.quest)
+		want := base
+		if test.boosted {
+			want = base + base/2
+		}
+		if got := QuestXPWithEffects(test.quest, effects); got != want {
+			t.Errorf("%s: %d xp, want %d", test.name, got, want)
+		}
+	}
+}
@@ -0,0 +1,71 @@
+package functions
+
+import (
+	"testing"
+	"time"
+
+	"github.com/MultiX0/solo_leveling_system/storage"
+	"github.com/MultiX0/solo_leveling_system/storage/memory"
+	"github.com/MultiX0/solo_leveling_system/types"
+)
+
+// newTestStore is a memory store with the catalogue in it and one new
+// player, played out like in newEmptyTestStore.
+func newTestStore(t *testing.T, at time.Time) (*memory.Store, *types.Player) {
+	t.Helper()
+
+	store, player := newEmptyTestStore(t, at)
+
+	quests, err := storage.ReadQuests("../../quests.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	skills, err := storage.ReadSkills("../../skills.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err = storage.Seed(store, quests, skills); err != nil {
+		t.Fatal(err)
+	}
+
+	return store, player
+}
+
+// newEmptyTestStore is a memory store with nothing but one new player,
+// whose days are played out at the given time with seeded rolls. Tests
+// move the clock on by setting it again.
+func newEmptyTestStore(t *testing.T, at time.Time) (*memory.Store, *types.Player) {
+	t.Helper()
+
+	defer func(rolls RollSource, now func() time.Time) {
+		t.Cleanup(func() {
+			Rolls, clock = rolls, now
+			resetQuestCaches()
+		})
+	}(Rolls, clock)
+	Rolls = SeededRolls{Seed: 1}
+	clock = func() time.Time { return at }
+
+	// the caches outlive the store, and the next one numbers its quests
+	// all over again
+	resetQuestCaches()
+
+	store := memory.New()
+	player, err := store.CreatePlayer(&types.Player{Name: "Jinwoo", Gender: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return store, player
+}
+
+func resetQuestCaches() {
+	questCacheMux.Lock()
+	questCache = make(map[string]*types.Quest)
+	questCacheMux.Unlock()
+
+	poolCacheMux.Lock()
+	questPoolCache = make(map[storage.QuestKind][]types.Quest)
+	poolCacheMux.Unlock()
+}
@@ -0,0 +1,270 @@
+package functions
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/MultiX0/solo_leveling_system/storage"
+	"github.com/MultiX0/solo_leveling_system/types"
+)
+
+var ErrInvalidHistoryFilter = errors.New("status must be active, completed, expired or abandoned, priority a number, from and to a date or RFC 3339 time, limit 1 to 100 and cursor a next_cursor given for the same filter")
+
+const (
+	DefaultHistoryLimit = 20
+	MaxHistoryLimit     = 100
+)
+
+// questStatuses are the names the history uses for player_quests.status.
+var questStatuses = map[int]string{
+	types.QuestAbandoned: "abandoned",
+	types.QuestActive:    "active",
+	types.QuestCompleted: "completed",
+	types.QuestExpired:   "expired",
+}
+
+// HistoryFilter is the history query as the player sent it. Every field
+// is optional.
+type HistoryFilter struct {
+	Status   []string
+	Priority []string
+	// From and To are dates, taken as the player's days, or RFC 3339
+	// times. Both ends are included.
+	From   string
+	To     string
+	Limit  string
+	Cursor string
+}
+
+// HistoryEntry is one quest the player was given.
+type HistoryEntry struct {
+	ID           int                  `json:"id"`
+	Quest        *types.Quest         `json:"quest"`
+	Status       string               `json:"status"`
+	Priority     int                  `json:"priority"`
+	Cadence      string               `json:"cadence"`
+	StartAt      time.Time            `json:"start_at"`
+	Deadline     time.Time            `json:"deadline"`
+	Progress     []*ObjectiveProgress `json:"progress,omitempty"`
+	RerolledFrom int                  `json:"rerolled_from,omitempty"`
+}
+
+// QuestHistory is one page of the player's quests, newest first. Total
+// and Counts cover every page, NextCursor is empty on the last one.
+type QuestHistory struct {
+	Quests     []*HistoryEntry `json:"quests"`
+	Total      int             `json:"total"`
+	Counts     map[string]int  `json:"counts"`
+	NextCursor string          `json:"next_cursor,omitempty"`
+}
+
+// filterHash sums up the filter a page was listed with, so that a cursor
+// is only taken back with the same one. Page sizes can change between
+// pages.
+func filterHash(filter storage.PlayerQuestFilter) string {
+	status := append([]int{}, filter.Status...)
+	sort.Ints(status)
+	priority := append([]int{}, filter.Priority...)
+	sort.Ints(priority)
+
+	hash := fnv.New64a()
+	fmt.Fprintf(hash, "%v/%v/%s/%s", status, priority,
+		filter.StartedAfter.UTC().Format(time.RFC3339Nano), filter.StartedBefore.UTC().Format(time.RFC3339Nano))
+	return strconv.FormatUint(hash.Sum64(), 36)
+}
+
+func encodeCursor(pq *types.PlayerQuest, filter storage.PlayerQuestFilter) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(fmt.Sprintf("%d:%d:%s", pq.StartAt.UnixNano(), pq.ID, filterHash(filter))))
+}
+
+func decodeCursor(cursor string, filter storage.PlayerQuestFilter) (*storage.PlayerQuestCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, ErrInvalidHistoryFilter
+	}
+
+	parts := strings.Split(string(raw), ":")
+	if len(parts) != 3 || parts[2] != filterHash(filter) {
+		return nil, ErrInvalidHistoryFilter
+	}
+	startAt, id := parts[0], parts[1]
+
+	nanos, err := strconv.ParseInt(startAt, 10, 64)
+	if err != nil {
+		return nil, ErrInvalidHistoryFilter
+	}
+
+	pqId, err := strconv.Atoi(id)
+	if err != nil {
+		return nil, ErrInvalidHistoryFilter
+	}
+
+	return &storage.PlayerQuestCursor{StartAt: time.Unix(0, nanos), ID: pqId}, nil
+}
+
+// historyTime parses a from or to bound. A date is the player's day, so
+// to ends when the next one starts.
+func historyTime(player *types.Player, value string, end bool) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		if end {
+			// start_at is matched before the bound, so this includes it
+			t = t.Add(time.Nanosecond)
+		}
+		return t, nil
+	}
+
+	day, err := time.Parse(time.DateOnly, value)
+	if err != nil {
+		return time.Time{}, ErrInvalidHistoryFilter
+	}
+
+	year, month, date := day.Date()
+	if end {
+		date++
+	}
+
+	loc, hour := scheduleAt(player, day)
+	return time.Date(year, month, date, hour, 0, 0, 0, loc), nil
+}
+
+// historyFilter turns the player's query into a storage filter and the
+// page size.
+func historyFilter(player *types.Player, query HistoryFilter) (storage.PlayerQuestFilter, int, error) {
+	filter := storage.PlayerQuestFilter{PlayerID: player.ID}
+
+	for _, name := range query.Status {
+		found := false
+		for status, statusName := range questStatuses {
+			if name == statusName {
+				filter.Status = append(filter.Status, status)
+				found = true
+			}
+		}
+		if !found {
+			return filter, 0, ErrInvalidHistoryFilter
+		}
+	}
+
+	for _, value := range query.Priority {
+		priority, err := strconv.Atoi(value)
+		if err != nil {
+			return filter, 0, ErrInvalidHistoryFilter
+		}
+		filter.Priority = append(filter.Priority, priority)
+	}
+
+	var err error
+	if query.From != "" {
+		if filter.StartedAfter, err = historyTime(player, query.From, false); err != nil {
+			return filter, 0, err
+		}
+	}
+	if query.To != "" {
+		if filter.StartedBefore, err = historyTime(player, query.To, true); err != nil {
+			return filter, 0, err
+		}
+	}
+
+	limit := DefaultHistoryLimit
+	if query.Limit != "" {
+		limit, err = strconv.Atoi(query.Limit)
+		if err != nil || limit < 1 || limit > MaxHistoryLimit {
+			return filter, 0, ErrInvalidHistoryFilter
+		}
+	}
+
+	if query.Cursor != "" {
+		if filter.After, err = decodeCursor(query.Cursor, filter); err != nil {
+			return filter, 0, err
+		}
+	}
+
+	return filter, limit, nil
+}
+
+func newHistoryEntry(store storage.Storage, pq *types.PlayerQuest) (*HistoryEntry, error) {
+	entry := &HistoryEntry{
+		ID:           pq.ID,
+		Status:       questStatuses[pq.Status],
+		Priority:     pq.Priority,
+		Cadence:      pq.Cadence,
+		StartAt:      pq.StartAt,
+		Deadline:     pq.Deadline,
+		RerolledFrom: pq.RerolledFrom,
+	}
+
+	// the row outlived its quest
+	if pq.QuestID == 0 {
+		return entry, nil
+	}
+
+	quest, err := getQuestByID(store, strconv.Itoa(pq.QuestID))
+	if err != nil {
+		return nil, err
+	}
+
+	entry.Quest = renderedQuest(quest, pq)
+	if len(entry.Quest.Objectives) > 0 {
+		entry.Progress = questObjectives(entry.Quest, pq)
+	}
+
+	return entry, nil
+}
+
+// GetQuestHistory lists every quest the player was given, newest first, a
+// page at a time.
+func GetQuestHistory(store storage.Storage, playerId string, query HistoryFilter) (*QuestHistory, error) {
+	id, err := strconv.Atoi(playerId)
+	if err != nil {
+		return nil, err
+	}
+
+	player, err := store.GetPlayer(id)
+	if err != nil {
+		return nil, err
+	}
+
+	filter, limit, err := historyFilter(player, query)
+	if err != nil {
+		return nil, err
+	}
+
+	counts, err := store.CountPlayerQuests(filter)
+	if err != nil {
+		return nil, err
+	}
+
+	// one more than the page tells whether there is a next one
+	filter.Limit = limit + 1
+	given, err := store.ListPlayerQuests(filter)
+	if err != nil {
+		return nil, err
+	}
+
+	history := &QuestHistory{Quests: []*HistoryEntry{}, Counts: map[string]int{}}
+	for status, name := range questStatuses {
+		history.Counts[name] = counts[status]
+		history.Total += counts[status]
+	}
+
+	if len(given) > limit {
+		given = given[:limit]
+		history.NextCursor = encodeCursor(given[limit-1], filter)
+	}
+
+	for _, pq := range given {
+		entry, err := newHistoryEntry(store, pq)
+		if err != nil {
+			return nil, err
+		}
+		history.Quests = append(history.Quests, entry)
+	}
+
+	return history, nil
+}
@@ -0,0 +1,216 @@
+package functions
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/MultiX0/solo_leveling_system/storage"
+	"github.com/MultiX0/solo_leveling_system/types"
+)
+
+var (
+	ErrNoObjectives    = errors.New("this quest has no objectives to report progress on")
+	ErrInvalidProgress = errors.New("progress must be positive amounts of the quest's objectives")
+)
+
+// progressBarWidth is how many cells a progress bar has.
+const progressBarWidth = 10
+
+// ObjectiveProgress is how far a player got with one quest objective.
+type ObjectiveProgress struct {
+	Name    string  `json:"name"`
+	Unit    string  `json:"unit"`
+	Target  float64 `json:"target"`
+	Done    float64 `json:"done"`
+	Percent int     `json:"percent"`
+	// Bar draws Percent, e.g. "[####------] 40%".
+	Bar string `json:"bar"`
+}
+
+func newObjectiveProgress(objective types.Objective, done float64) *ObjectiveProgress {
+	percent := 100
+	if objective.Target > 0 {
+		percent = min(int(math.Floor(done/objective.Target*100)), 100)
+	}
+
+	filled := percent * progressBarWidth / 100
+
+	return &ObjectiveProgress{
+		Name:    objective.Name,
+		Unit:    objective.Unit,
+		Target:  objective.Target,
+		Done:    done,
+		Percent: percent,
+		Bar:     fmt.Sprintf("[%s%s] %d%%", strings.Repeat("#", filled), strings.Repeat("-", progressBarWidth-filled), percent),
+	}
+}
+
+// questObjectives pairs the quest's objectives with the player's progress.
+func questObjectives(quest *types.Quest, pq *types.PlayerQuest) []*ObjectiveProgress {
+	objectives := []*ObjectiveProgress{}
+	for i, objective := range quest.Objectives {
+		var done float64
+		if i < len(pq.Progress) {
+			done = pq.Progress[i]
+		}
+		objectives = append(objectives, newObjectiveProgress(objective, done))
+	}
+	return objectives
+}
+
+// ActiveQuest is a quest as shown to the player while it is active.
+type ActiveQuest struct {
+	*types.Quest
+	Progress []*ObjectiveProgress `json:"progress,omitempty"`
+}
+
+// WithProgress shows the player's active quests as they were rendered for
+// them, together with their objective progress.
+func WithProgress(store storage.Storage, playerId string, quests ...*types.Quest) ([]*ActiveQuest, error) {
+	id, err := strconv.Atoi(playerId)
+	if err != nil {
+		return nil, err
+	}
+
+	active, err := store.ListPlayerQuests(storage.PlayerQuestFilter{
+		PlayerID: id,
+		Status:   []int{types.QuestActive},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	byQuest := map[int]*types.PlayerQuest{}
+	for _, pq := range active {
+		byQuest[pq.QuestID] = pq
+	}
+
+	withProgress := []*ActiveQuest{}
+	for _, quest := range quests {
+		aq := &ActiveQuest{Quest: quest}
+		if pq, ok := byQuest[quest.ID]; ok {
+			aq.Quest = renderedQuest(quest, pq)
+			if len(aq.Objectives) > 0 {
+				aq.Progress = questObjectives(aq.Quest, pq)
+			}
+		}
+		withProgress = append(withProgress, aq)
+	}
+
+	return withProgress, nil
+}
+
+// QuestProgress is the outcome of reporting progress on a quest. Reward is
+// only set when the report completed the quest.
+type QuestProgress struct {
+	Quest      *types.Quest         `json:"quest"`
+	Objectives []*ObjectiveProgress `json:"objectives"`
+	Completed  bool                 `json:"completed"`
+	Reward     *QuestReward         `json:"reward,omitempty"`
+}
+
+// ReportProgress adds amounts, keyed by objective name, to the player's
+// active quest. Once every objective reaches its target the quest is
+// completed and rewarded like FinishQuest does, in the same transaction.
+func ReportProgress(store storage.Storage, playerId, questId string, amounts map[string]float64) (*QuestProgress, error) {
+	pId, err := strconv.Atoi(playerId)
+	if err != nil {
+		return nil, err
+	}
+
+	qId, err := strconv.Atoi(questId)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(amounts) == 0 {
+		return nil, ErrInvalidProgress
+	}
+
+	var progress *QuestProgress
+	err = store.Atomic(func(tx storage.Storage) error {
+		active, err := tx.ListPlayerQuests(storage.PlayerQuestFilter{
+			PlayerID: pId,
+			QuestID:  qId,
+			Status:   []int{types.QuestActive},
+			Limit:    1,
+		})
+		if err != nil {
+			return err
+		}
+
+		if len(active) == 0 {
+			return questNotActiveError(tx, pId, qId)
+		}
+		pq := active[0]
+
+		template, err := getQuestByID(tx, questId)
+		if err != nil {
+			return err
+		}
+
+		quest := renderedQuest(template, pq)
+		if len(quest.Objectives) == 0 {
+			return ErrNoObjectives
+		}
+
+		done := make([]float64, len(quest.Objectives))
+		copy(done, pq.Progress)
+
+		for name, amount := range amounts {
+			i := objectiveIndex(quest, name)
+			if i < 0 || amount <= 0 {
+				return fmt.Errorf("%w, got %q: %v", ErrInvalidProgress, name, amount)
+			}
+			done[i] = min(done[i]+amount, quest.Objectives[i].Target)
+		}
+
+		if err = tx.UpdatePlayerQuestProgress(pq.ID, done); err != nil {
+			return err
+		}
+		pq.Progress = done
+
+		progress = &QuestProgress{
+			Quest:      quest,
+			Objectives: questObjectives(quest, pq),
+		}
+
+		for i, objective := range quest.Objectives {
+			if done[i] < objective.Target {
+				return nil
+			}
+		}
+
+		count, err := tx.UpdatePlayerQuestStatus(storage.PlayerQuestFilter{
+			ID:     pq.ID,
+			Status: []int{types.QuestActive},
+		}, types.QuestCompleted)
+		if err != nil {
+			return err
+		}
+		if count == 0 {
+			return ErrQuestNotActive
+		}
+
+		progress.Completed = true
+		progress.Reward, err = grantQuestReward(tx, pId, quest)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return progress, nil
+}
+
+func objectiveIndex(quest *types.Quest, name string) int {
+	for i, objective := range quest.Objectives {
+		if strings.EqualFold(objective.Name, name) {
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,151 @@
+package functions
+
+import (
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/MultiX0/solo_leveling_system/storage"
+	"github.com/MultiX0/solo_leveling_system/types"
+)
+
+var (
+	// PenaltyDeadline is how long a player has to clear the Penalty Zone.
+	PenaltyDeadline = 4 * time.Hour
+	// MaxPenaltyEscalation caps how much harder failed penalties get.
+	MaxPenaltyEscalation = 5
+)
+
+// PenaltyZone is the Penalty Zone quest a player is stuck with.
+type PenaltyZone struct {
+	Quest *ActiveQuest `json:"quest"`
+	// Escalation multiplies the quest's targets and what failing it costs.
+	Escalation int       `json:"escalation"`
+	Deadline   time.Time `json:"deadline"`
+}
+
+// failedPenaltyStreak counts the player's Penalty Zone quests that expired
+// in a row, newest first, ignoring the active one.
+func failedPenaltyStreak(store storage.Storage, playerId int) (int, error) {
+	penalties, err := store.ListPlayerQuests(storage.PlayerQuestFilter{
+		PlayerID: playerId,
+		Kind:     storage.PenaltyQuests,
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	streak := 0
+	for _, pq := range penalties {
+		if pq.Status == types.QuestActive {
+			continue
+		}
+		if pq.Status != types.QuestExpired {
+			break
+		}
+		streak++
+	}
+
+	return streak, nil
+}
+
+func penaltyEscalation(streak int) int {
+	return min(streak+1, MaxPenaltyEscalation)
+}
+
+func activePenalty(store storage.Storage, playerId int) (*types.PlayerQuest, error) {
+	active, err := store.ListPlayerQuests(storage.PlayerQuestFilter{
+		PlayerID: playerId,
+		Status:   []int{types.QuestActive},
+		Kind:     storage.PenaltyQuests,
+		Limit:    1,
+	})
+	if err != nil || len(active) == 0 {
+		return nil, err
+	}
+
+	return active[0], nil
+}
+
+// assignPenaltyQuest sends the player to the Penalty Zone, unless they are
+// already there. Every penalty failed in a row before makes the new one
+// harder. It is meant to run inside Atomic.
+func assignPenaltyQuest(tx storage.Storage, playerId int) error {
+	active, err := activePenalty(tx, playerId)
+	if err != nil || active != nil {
+		return err
+	}
+
+	if questPoolSize(tx, storage.PenaltyQuests, playerId) == 0 {
+		log.Println("no penalty quests to give")
+		return nil
+	}
+
+	quest, err := fetchQuest(tx, storage.PenaltyQuests, playerId)
+	if err != nil {
+		return err
+	}
+
+	streak, err := failedPenaltyStreak(tx, playerId)
+	if err != nil {
+		return err
+	}
+
+	player, err := tx.GetPlayer(playerId)
+	if err != nil {
+		return err
+	}
+
+	level, _ := Curve.Level(player.XP)
+	description, objectives := renderQuest(quest, level, float64(penaltyEscalation(streak)))
+
+	startAt := clock()
+	_, err = tx.CreatePlayerQuest(&types.PlayerQuest{
+		StartAt:  startAt,
+		PlayerID: playerId,
+		QuestID:  quest.ID,
+		Status:   types.QuestActive,
+		Priority: types.PenaltyPriority,
+		Deadline: startAt.Add(PenaltyDeadline),
+
+		Description: description,
+		Objectives:  objectives,
+	})
+
+	return err
+}
+
+// GetPenaltyZone returns the player's active Penalty Zone quest, or nil
+// when they aren't in the Penalty Zone.
+func GetPenaltyZone(store storage.Storage, playerId string) (*PenaltyZone, error) {
+	id, err := strconv.Atoi(playerId)
+	if err != nil {
+		return nil, err
+	}
+
+	pq, err := activePenalty(store, id)
+	if err != nil || pq == nil {
+		return nil, err
+	}
+
+	quest, err := getQuestByID(store, strconv.Itoa(pq.QuestID))
+	if err != nil {
+		return nil, err
+	}
+
+	withProgress, err := WithProgress(store, playerId, quest)
+	if err != nil {
+		return nil, err
+	}
+
+	streak, err := failedPenaltyStreak(store, id)
+	if err != nil {
+		return nil, err
+	}
+
+	return &PenaltyZone{
+		Quest:      withProgress[0],
+		Escalation: penaltyEscalation(streak),
+		Deadline:   pq.Deadline,
+	}, nil
+}
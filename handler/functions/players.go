@@ -1,27 +1,15 @@
 package functions
 
 import (
-	"encoding/json"
 	"log"
+	"strconv"
 
-	"github.com/MultiX0/solo_leveling_system/db"
+	"github.com/MultiX0/solo_leveling_system/storage"
 	"github.com/MultiX0/solo_leveling_system/types"
-	"github.com/MultiX0/solo_leveling_system/utils"
 )
 
-func CreateNewPlayer(player *types.Player) (*types.Player, error) {
-	data, err := utils.InsertToDB("players", map[string]any{
-		"name":   player.Name,
-		"gender": player.Gender,
-	})
-	if err != nil {
-		log.Println(err)
-		return nil, err
-	}
-
-	var newPlayer types.Player
-
-	err = json.Unmarshal(data, &newPlayer)
+func CreateNewPlayer(store storage.Storage, player *types.Player) (*types.Player, error) {
+	newPlayer, err := store.CreatePlayer(player)
 	if err != nil {
 		log.Println(err)
 		return nil, err
@@ -29,24 +17,21 @@ func CreateNewPlayer(player *types.Player) (*types.Player, error) {
 
 	log.Println(newPlayer)
 
-	return &newPlayer, nil
+	return newPlayer, nil
 }
 
-func GetPlayerByID(id string) (*types.Player, error) {
-	data, _, err := db.SupabaseClient.From("players").Select("*", "exact", false).Eq("id", id).Single().Execute()
-
+func GetPlayerByID(store storage.Storage, id string) (*types.Player, error) {
+	playerId, err := strconv.Atoi(id)
 	if err != nil {
-		log.Println(err)
 		return nil, err
 	}
 
-	var player types.Player
-	err = json.Unmarshal(data, &player)
+	player, err := store.GetPlayer(playerId)
 	if err != nil {
 		log.Println(err)
 		return nil, err
 	}
 
-	return &player, nil
+	return player, nil
 
 }
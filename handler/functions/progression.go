@@ -0,0 +1,161 @@
+package functions
+
+import (
+	"log"
+	"math"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/MultiX0/solo_leveling_system/storage"
+	"github.com/MultiX0/solo_leveling_system/types"
+)
+
+// LevelCurve gives the XP needed to go from level n to n+1 as
+// Base * n^Exponent, so every level takes a bit longer than the last.
+type LevelCurve struct {
+	Base     float64
+	Exponent float64
+}
+
+var (
+	Curve = LevelCurve{Base: 100, Exponent: 1.5}
+	// QuestXP is multiplied by the quest's priority weight on completion.
+	QuestXP = 50
+	// PunishmentXP is taken away for every quest that expires.
+	PunishmentXP = 250
+	// StatPointsPerLevel are granted the first time a level is reached.
+	StatPointsPerLevel = 5
+	// ManaPerIntelligence is the max mana each point of intelligence gives.
+	ManaPerIntelligence = 10
+	// ManaRegenPerMinute is how fast spent mana comes back.
+	ManaRegenPerMinute = 1.0
+)
+
+// InitProgression reads the optional overrides of the XP settings.
+func InitProgression() {
+	Curve.Base = envFloat("LEVEL_CURVE_BASE", Curve.Base)
+	Curve.Exponent = envFloat("LEVEL_CURVE_EXPONENT", Curve.Exponent)
+	QuestXP = int(envFloat("QUEST_XP", float64(QuestXP)))
+	PunishmentXP = int(envFloat("PUNISHMENT_XP", float64(PunishmentXP)))
+	StatPointsPerLevel = int(envFloat("STAT_POINTS_PER_LEVEL", float64(StatPointsPerLevel)))
+	ManaPerIntelligence = int(envFloat("MANA_PER_INTELLIGENCE", float64(ManaPerIntelligence)))
+	ManaRegenPerMinute = envFloat("MANA_REGEN_PER_MINUTE", ManaRegenPerMinute)
+	WeeklyMultiplier = int(envFloat("WEEKLY_MULTIPLIER", float64(WeeklyMultiplier)))
+	MonthlyMultiplier = int(envFloat("MONTHLY_MULTIPLIER", float64(MonthlyMultiplier)))
+	MaxRerollsPerDay = int(envFloat("MAX_REROLLS_PER_DAY", float64(MaxRerollsPerDay)))
+	RerollCostXP = int(envFloat("REROLL_COST_XP", float64(RerollCostXP)))
+	QuestCooldown = time.Duration(envFloat("QUEST_COOLDOWN_DAYS", QuestCooldown.Hours()/24) * float64(24*time.Hour))
+	PenaltyDeadline = time.Duration(envFloat("PENALTY_DEADLINE_HOURS", PenaltyDeadline.Hours()) * float64(time.Hour))
+}
+
+func envFloat(key string, fallback float64) float64 {
+	value := os.Getenv(key)
+	if value == "" {
+		return fallback
+	}
+
+	f, err := strconv.ParseFloat(value, 64)
+	if err != nil || f < 0 {
+		log.Printf("ignoring %s=%q, using %v", key, value, fallback)
+		return fallback
+	}
+
+	return f
+}
+
+// XPForLevel is the XP needed to go from level to level+1.
+func (c LevelCurve) XPForLevel(level int) int {
+	xp := int(math.Round(c.Base * math.Pow(float64(level), c.Exponent)))
+	if xp < 1 {
+		return 1
+	}
+	return xp
+}
+
+// Level returns the level reached with xp and how much XP is still
+// missing for the next one. Everybody starts at level 1.
+func (c LevelCurve) Level(xp int) (int, int) {
+	level := 1
+	for {
+		needed := c.XPForLevel(level)
+		if xp < needed {
+			return level, needed - xp
+		}
+		xp -= needed
+		level++
+	}
+}
+
+// GrantXP adds xp to the player and hands out StatPointsPerLevel for every
+// level reached for the first time. It returns the updated player and how
+// many new levels were reached.
+func GrantXP(store storage.Storage, playerId int, xp int) (*types.Player, int, error) {
+	player, err := store.AddPlayerXP(playerId, xp)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	level, _ := Curve.Level(player.XP)
+	if level <= player.MaxLevel {
+		return player, 0, nil
+	}
+
+	gained := level - player.MaxLevel
+	applied, err := store.LevelUpPlayer(playerId, player.MaxLevel, level, gained*StatPointsPerLevel)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	// somebody else already paid these levels out
+	if !applied {
+		gained = 0
+	}
+
+	player, err = store.GetPlayer(playerId)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return player, gained, nil
+}
+
+// TotalXP is the XP needed to get from nothing to the given level.
+func (c LevelCurve) TotalXP(level int) int {
+	total := 0
+	for l := 1; l < level; l++ {
+		total += c.XPForLevel(l)
+	}
+	return total
+}
+
+// MaxQuestPriority is the lowest priority a quest can have, priority 1
+// being the main quest.
+const MaxQuestPriority = 5
+
+// priorityWeight is how many times QuestXP a quest of the priority pays:
+// MaxQuestPriority for the main quest down to 1 for the least important
+// side quests.
+func priorityWeight(priority int) int {
+	return max(MaxQuestPriority+1-priority, 1)
+}
+
+func QuestXPReward(quest *types.Quest) int {
+	return QuestXP * priorityWeight(quest.Priority) * cadenceMultiplier(quest.Cadence) * triggerMultiplier(quest)
+}
+
+// PlayerProgress is the level information shown next to a player.
+type PlayerProgress struct {
+	XP            int `json:"xp"`
+	Level         int `json:"level"`
+	XPToNextLevel int `json:"xp_to_next_level"`
+}
+
+func GetPlayerProgress(player *types.Player) PlayerProgress {
+	level, toNext := Curve.Level(player.XP)
+	return PlayerProgress{
+		XP:            player.XP,
+		Level:         level,
+		XPToNextLevel: toNext,
+	}
+}
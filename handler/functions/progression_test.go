@@ -0,0 +1,26 @@
+package functions
+
+import (
+	"testing"
+
+	"github.com/MultiX0/solo_leveling_system/types"
+)
+
+func TestMainQuestPaysTheMost(t *testing.T) {
+	main := QuestXPReward(&types.Quest{Priority: 1, Cadence: types.CadenceDaily})
+
+	previous := main
+	for priority := 2; priority <= MaxQuestPriority; priority++ {
+		xp := QuestXPReward(&types.Quest{Priority: priority, Cadence: types.CadenceDaily})
+		if xp > main {
+			t.Errorf("priority %d side quest pays %d xp, more than the main quest's %d", priority, xp, main)
+		}
+		if xp >= previous {
+			t.Errorf("priority %d pays %d xp, not less than priority %d's %d", priority, xp, priority-1, previous)
+		}
+		if xp <= 0 {
+			t.Errorf("priority %d pays %d xp", priority, xp)
+		}
+		previous = xp
+	}
+}
@@ -1,25 +1,22 @@
 package functions
 
 import (
-	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
-	"math/rand"
 	"runtime"
 	"strconv"
 	"sync"
 	"time"
 
-	"github.com/MultiX0/solo_leveling_system/db"
+	"github.com/MultiX0/solo_leveling_system/storage"
 	"github.com/MultiX0/solo_leveling_system/types"
-	"github.com/MultiX0/solo_leveling_system/utils"
-	"github.com/supabase-community/postgrest-go"
 )
 
 var (
 	questCache     = make(map[string]*types.Quest)
 	questCacheMux  sync.RWMutex
-	questPoolCache = make(map[bool][]types.Quest)
+	questPoolCache = make(map[storage.QuestKind][]types.Quest)
 	poolCacheMux   sync.RWMutex
 )
 
@@ -35,7 +32,7 @@ func releaseWorker() {
 }
 
 // Cached quest retrieval
-func getQuestByID(id string) (*types.Quest, error) {
+func getQuestByID(store storage.Storage, id string) (*types.Quest, error) {
 	questCacheMux.RLock()
 	if quest, exists := questCache[id]; exists {
 		questCacheMux.RUnlock()
@@ -43,13 +40,12 @@ func getQuestByID(id string) (*types.Quest, error) {
 	}
 	questCacheMux.RUnlock()
 
-	data, _, err := db.SupabaseClient.From("quests").Select("*", "", false).Eq("id", id).Single().Execute()
+	questId, err := strconv.Atoi(id)
 	if err != nil {
 		return nil, err
 	}
 
-	var quest *types.Quest
-	err = json.Unmarshal(data, &quest)
+	quest, err := store.GetQuest(questId)
 	if err != nil {
 		return nil, err
 	}
@@ -61,75 +57,89 @@ func getQuestByID(id string) (*types.Quest, error) {
 	return quest, nil
 }
 
-func lazyInitQuestPool(main bool) {
+func lazyInitQuestPool(store storage.Storage, kind storage.QuestKind) {
 	poolCacheMux.Lock()
 	defer poolCacheMux.Unlock()
 
 	// Check if pool is already populated
-	if _, exists := questPoolCache[main]; exists {
+	if _, exists := questPoolCache[kind]; exists {
 		return
 	}
 
-	quests, err := fetchQuestPool(main)
+	quests, err := store.ListQuests(kind)
 	if err != nil {
 		log.Println(err)
 		return
 	}
 
-	questPoolCache[main] = quests
+	questPoolCache[kind] = quests
 }
 
-func fetchQuestPool(main bool) ([]types.Quest, error) {
-	var data []byte
-	var err error
+// questPool is the cached pool of quests everyone gets plus the player's
+// own quests of the kind, which aren't cached since they can change at any
+// time.
+func questPool(store storage.Storage, kind storage.QuestKind, playerId int) ([]types.Quest, error) {
+	lazyInitQuestPool(store, kind)
 
-	if main {
-		data, _, err = db.SupabaseClient.From("quests").Select("*", "exact", false).Eq("priority", "1").Execute()
-	} else {
-		data, _, err = db.SupabaseClient.From("quests").Select("*", "exact", false).Gt("priority", "1").Execute()
+	pool := []types.Quest{}
+	poolCacheMux.RLock()
+	for _, quest := range questPoolCache[kind] {
+		// later chain steps are only given once the step before is done
+		if quest.ChainStep <= 1 {
+			pool = append(pool, quest)
+		}
 	}
+	poolCacheMux.RUnlock()
 
+	custom, err := store.ListCustomQuests(playerId, kind)
 	if err != nil {
 		return nil, err
 	}
 
-	var quests []types.Quest
-	if err = json.Unmarshal(data, &quests); err != nil {
-		return nil, err
-	}
-
-	return quests, nil
+	return append(pool, custom...), nil
 }
 
-// Cached quest pool retrieval
-func fetchQuest(main bool) (*types.Quest, error) {
-	poolCacheMux.RLock()
-	if pool, exists := questPoolCache[main]; exists && len(pool) > 0 {
-		poolCacheMux.RUnlock()
-		random := rand.Intn(len(pool))
-		return &pool[random], nil
+// fetchQuest picks a quest of the kind from the player's pool by weight.
+// It never picks one of picked and, as long as the pool has others, none
+// the player got recently or of a category already picked.
+func fetchQuest(store storage.Storage, kind storage.QuestKind, playerId int, picked ...*types.Quest) (*types.Quest, error) {
+	pool, err := questPool(store, kind, playerId)
+	if err != nil {
+		return nil, err
 	}
-	poolCacheMux.RUnlock()
 
-	// Lazy load the quest pool if not exists
-	lazyInitQuestPool(main)
+	recent, err := recentQuests(store, playerId)
+	if err != nil {
+		return nil, err
+	}
 
-	poolCacheMux.RLock()
-	defer poolCacheMux.RUnlock()
+	rng, err := questRand(store, kind, playerId)
+	if err != nil {
+		return nil, err
+	}
 
-	pool, exists := questPoolCache[main]
-	if !exists || len(pool) == 0 {
-		return nil, fmt.Errorf("no quests found")
+	quest := selectQuest(rng, pool, picked, recent)
+	if quest == nil {
+		return nil, errNoQuests
 	}
 
-	random := rand.Intn(len(pool))
-	return &pool[random], nil
+	return quest, nil
 }
 
-func GetMainQuest(id string) (*types.Quest, error, time.Time) {
+// forgetQuest drops a quest that changed from the quest cache.
+func forgetQuest(id int) {
+	questCacheMux.Lock()
+	delete(questCache, strconv.Itoa(id))
+	questCacheMux.Unlock()
+}
+
+// GetMainQuest returns the player's active main quest and its deadline,
+// handing out a new one when there is none. The quest is nil when today's
+// main quest is already done or the player is in the Penalty Zone.
+func GetMainQuest(store storage.Storage, id string) (*types.Quest, error, time.Time) {
 	var quest *types.Quest
 	var err error
-	var startTime time.Time
+	var deadline time.Time
 	var mu sync.Mutex
 	var wg sync.WaitGroup
 
@@ -147,13 +157,28 @@ func GetMainQuest(id string) (*types.Quest, error, time.Time) {
 			}
 		}()
 
-		completedQuestData, count, queryErr := db.SupabaseClient.From("player_quests").
-			Select("*", "exact", false).Order("start_at", &postgrest.OrderOpts{Ascending: false}).
-			Eq("player", id).
-			Eq("status", "1").
-			Eq("priority", "1").
-			Limit(1, "").
-			Execute()
+		playerId, convErr := strconv.Atoi(id)
+		if convErr != nil {
+			mu.Lock()
+			err = convErr
+			mu.Unlock()
+			return
+		}
+
+		player, playerErr := store.GetPlayer(playerId)
+		if playerErr != nil {
+			mu.Lock()
+			err = playerErr
+			mu.Unlock()
+			return
+		}
+
+		completedQuest, queryErr := store.ListPlayerQuests(storage.PlayerQuestFilter{
+			PlayerID: playerId,
+			Status:   []int{types.QuestCompleted},
+			Kind:     storage.MainQuests,
+			Limit:    1,
+		})
 
 		if queryErr != nil {
 			mu.Lock()
@@ -162,30 +187,22 @@ func GetMainQuest(id string) (*types.Quest, error, time.Time) {
 			return
 		}
 
-		if count > 0 {
-			var completedQuest []*types.PlayerQuest
-			if unmarshalErr := json.Unmarshal(completedQuestData, &completedQuest); unmarshalErr != nil {
-				mu.Lock()
-				err = unmarshalErr
-				mu.Unlock()
-				return
-			}
-			if time.Since(completedQuest[0].StartAt) < 24*time.Hour {
+		if len(completedQuest) > 0 {
+			if !completedQuest[0].StartAt.Before(playerDayStart(player, clock())) {
 				mu.Lock()
 				quest = nil
-				startTime = time.Time{}
+				deadline = time.Time{}
 				mu.Unlock()
 				return
 			}
 		}
 
-		currentQuestsData, count, queryErr := db.SupabaseClient.From("player_quests").
-			Select("*", "exact", false).Order("start_at", &postgrest.OrderOpts{Ascending: false}).
-			Eq("player", id).
-			Eq("status", "0").
-			Eq("priority", "1").
-			Limit(1, "").
-			Execute()
+		data, queryErr := store.ListPlayerQuests(storage.PlayerQuestFilter{
+			PlayerID: playerId,
+			Status:   []int{types.QuestActive},
+			Kind:     storage.MainQuests,
+			Limit:    1,
+		})
 
 		if queryErr != nil {
 			mu.Lock()
@@ -194,15 +211,31 @@ func GetMainQuest(id string) (*types.Quest, error, time.Time) {
 			return
 		}
 
-		if count == 0 {
-			newQuest, fetchErr := fetchQuest(true)
+		if len(data) == 0 {
+			// no new main quests until the Penalty Zone is cleared
+			penalty, penaltyErr := activePenalty(store, playerId)
+			if penaltyErr != nil || penalty != nil {
+				mu.Lock()
+				err = penaltyErr
+				mu.Unlock()
+				return
+			}
+
+			effects, effectsErr := PassiveEffects(store, playerId)
+			if effectsErr != nil {
+				mu.Lock()
+				err = effectsErr
+				mu.Unlock()
+				return
+			}
+			newQuest, fetchErr := fetchQuest(store, storage.MainQuests, playerId)
 			if fetchErr != nil {
 				mu.Lock()
 				err = fetchErr
 				mu.Unlock()
 				return
 			}
-			insertErr := insertQuestToPlayerQuests(newQuest, id)
+			pq, insertErr := insertQuestToPlayerQuests(store, newQuest, playerId, effects)
 			if insertErr != nil {
 				mu.Lock()
 				err = insertErr
@@ -211,21 +244,12 @@ func GetMainQuest(id string) (*types.Quest, error, time.Time) {
 			}
 			mu.Lock()
 			quest = newQuest
-			startTime = time.Now()
-			mu.Unlock()
-			return
-		}
-
-		var data []*types.PlayerQuest
-		unmarshalErr := json.Unmarshal(currentQuestsData, &data)
-		if unmarshalErr != nil {
-			mu.Lock()
-			err = unmarshalErr
+			deadline = pq.Deadline
 			mu.Unlock()
 			return
 		}
 
-		retrievedQuest, questErr := getQuestByID(strconv.Itoa(data[0].QuestID))
+		retrievedQuest, questErr := getQuestByID(store, strconv.Itoa(data[0].QuestID))
 		if questErr != nil {
 			mu.Lock()
 			err = questErr
@@ -235,18 +259,21 @@ func GetMainQuest(id string) (*types.Quest, error, time.Time) {
 
 		mu.Lock()
 		quest = retrievedQuest
-		startTime = data[0].StartAt
+		deadline = data[0].Deadline
 		mu.Unlock()
 	}()
 
 	wg.Wait()
-	return quest, err, startTime
+	return quest, err, deadline
 }
 
-func GetSideQuests(id string) ([]*types.Quest, error, time.Time) {
+// GetSideQuests returns the player's active side quests and the earliest
+// of their deadlines, handing out SideQuestSlots (plus what passive skills
+// add) new ones when there are none.
+func GetSideQuests(store storage.Storage, id string) ([]*types.Quest, error, time.Time) {
 	var quests []*types.Quest
 	var err error
-	var startTime time.Time
+	var deadline time.Time
 	var mu sync.Mutex
 	var wg sync.WaitGroup
 
@@ -264,14 +291,36 @@ func GetSideQuests(id string) ([]*types.Quest, error, time.Time) {
 			}
 		}()
 
-		completedQuestsData, count, queryErr := db.SupabaseClient.From("player_quests").
-			Select("*", "exact", false).
-			Eq("player", id).
-			Eq("status", "1").
-			Gt("priority", "1").
-			Order("start_at", &postgrest.OrderOpts{Ascending: false}).
-			Limit(2, "").
-			Execute()
+		playerId, convErr := strconv.Atoi(id)
+		if convErr != nil {
+			mu.Lock()
+			err = convErr
+			mu.Unlock()
+			return
+		}
+
+		player, playerErr := store.GetPlayer(playerId)
+		if playerErr != nil {
+			mu.Lock()
+			err = playerErr
+			mu.Unlock()
+			return
+		}
+
+		effects, effectsErr := PassiveEffects(store, playerId)
+		if effectsErr != nil {
+			mu.Lock()
+			err = effectsErr
+			mu.Unlock()
+			return
+		}
+		slots := SideQuestCount(effects)
+
+		data, queryErr := store.ListPlayerQuests(storage.PlayerQuestFilter{
+			PlayerID: playerId,
+			Status:   []int{types.QuestActive},
+			Kind:     storage.SideQuests,
+		})
 
 		if queryErr != nil {
 			mu.Lock()
@@ -280,53 +329,66 @@ func GetSideQuests(id string) ([]*types.Quest, error, time.Time) {
 			return
 		}
 
-		if count > 0 {
-			var completedQuests []*types.PlayerQuest
-			if unmarshalErr := json.Unmarshal(completedQuestsData, &completedQuests); unmarshalErr != nil {
+		// the day's side quests are done once none of those handed out
+		// today is active anymore, however many there were; abandoning one
+		// is giving up on it for the day
+		if len(data) == 0 {
+			today, queryErr := store.ListPlayerQuests(storage.PlayerQuestFilter{
+				PlayerID:     playerId,
+				Kind:         storage.SideQuests,
+				StartedAfter: playerDayStart(player, clock()),
+				Limit:        1,
+			})
+
+			if queryErr != nil {
 				mu.Lock()
-				err = unmarshalErr
+				err = queryErr
 				mu.Unlock()
 				return
 			}
-			allRecent := true
-			earliestStart := time.Now()
-			for _, quest := range completedQuests {
-				if quest.StartAt.Before(earliestStart) {
-					earliestStart = quest.StartAt
-				}
-				if time.Since(quest.StartAt) >= 24*time.Hour {
-					allRecent = false
-					break
-				}
-			}
-			if allRecent && len(completedQuests) == 2 {
+
+			if len(today) > 0 {
 				mu.Lock()
 				quests = []*types.Quest{}
-				startTime = earliestStart
+				deadline = time.Time{}
 				mu.Unlock()
 				return
 			}
 		}
 
-		currentQuestsData, count, queryErr := db.SupabaseClient.From("player_quests").
-			Select("*", "exact", false).
-			Eq("player", id).
-			Eq("status", "0").
-			Gt("priority", "1").
-			Execute()
+		if len(data) == 0 {
+			var tempQuests []*types.Quest
+			var earliestDeadline time.Time
 
-		if queryErr != nil {
-			mu.Lock()
-			err = queryErr
-			mu.Unlock()
-			return
-		}
+			// chains the player started go before random picks
+			chainQuests, chainErr := nextChainQuests(store, playerId)
+			if chainErr != nil {
+				mu.Lock()
+				err = chainErr
+				mu.Unlock()
+				return
+			}
 
-		if count == 0 {
-			var tempQuests []*types.Quest
-			currentTime := time.Now()
-			for len(tempQuests) < 2 {
-				quest, fetchErr := fetchQuest(false)
+			// every attempt hands out or skips another candidate, so
+			// there are never more attempts than candidates
+			size := questPoolSize(store, storage.SideQuests, playerId) + len(chainQuests)
+			if size > 0 {
+				slots = min(slots, size)
+			}
+			// picks skipped for a title already handed out, kept out of
+			// the next picks
+			var skipped []*types.Quest
+			for attempt := 0; len(tempQuests) < slots && attempt < max(size, 1); attempt++ {
+				var quest *types.Quest
+				var fetchErr error
+				if len(chainQuests) > 0 {
+					quest, chainQuests = chainQuests[0], chainQuests[1:]
+				} else {
+					quest, fetchErr = fetchQuest(store, storage.SideQuests, playerId, append(tempQuests, skipped...)...)
+				}
+				if errors.Is(fetchErr, errNoQuests) && len(tempQuests) > 0 {
+					break
+				}
 				if fetchErr != nil {
 					mu.Lock()
 					err = fetchErr
@@ -340,133 +402,289 @@ func GetSideQuests(id string) ([]*types.Quest, error, time.Time) {
 						break
 					}
 				}
-				if !duplicate {
+				if duplicate {
+					skipped = append(skipped, quest)
+				} else {
 					tempQuests = append(tempQuests, quest)
-					insertErr := insertQuestToPlayerQuests(quest, id)
+					pq, insertErr := insertQuestToPlayerQuests(store, quest, playerId, effects)
 					if insertErr != nil {
 						mu.Lock()
 						err = insertErr
 						mu.Unlock()
 						return
 					}
+					if earliestDeadline.IsZero() || pq.Deadline.Before(earliestDeadline) {
+						earliestDeadline = pq.Deadline
+					}
 				}
 			}
 			mu.Lock()
 			quests = tempQuests
-			startTime = currentTime
-			mu.Unlock()
-			return
-		}
-
-		var data []*types.PlayerQuest
-		unmarshalErr := json.Unmarshal(currentQuestsData, &data)
-		if unmarshalErr != nil {
-			mu.Lock()
-			err = unmarshalErr
+			deadline = earliestDeadline
 			mu.Unlock()
 			return
 		}
 
 		var tempQuests []*types.Quest
-		earliestStart := time.Now()
+		var earliestDeadline time.Time
 		for _, d := range data {
-			quest, questErr := getQuestByID(strconv.Itoa(d.QuestID))
+			quest, questErr := getQuestByID(store, strconv.Itoa(d.QuestID))
 			if questErr != nil {
 				mu.Lock()
 				err = questErr
 				mu.Unlock()
 				return
 			}
-			if d.StartAt.Before(earliestStart) {
-				earliestStart = d.StartAt
+			if earliestDeadline.IsZero() || d.Deadline.Before(earliestDeadline) {
+				earliestDeadline = d.Deadline
 			}
 			tempQuests = append(tempQuests, quest)
 		}
 
 		mu.Lock()
 		quests = tempQuests
-		startTime = earliestStart
+		deadline = earliestDeadline
 		mu.Unlock()
 	}()
 
 	wg.Wait()
-	return quests, err, startTime
+	return quests, err, deadline
 }
 
-func insertQuestToPlayerQuests(quest *types.Quest, playerId string) error {
-	id, err := strconv.Atoi(playerId)
+// questPoolSize keeps callers from looking for more distinct quests of a
+// kind than the player's pool has.
+func questPoolSize(store storage.Storage, kind storage.QuestKind, playerId int) int {
+	pool, err := questPool(store, kind, playerId)
 	if err != nil {
-		return err
+		log.Println(err)
+	}
+
+	return len(pool)
+}
+
+// insertQuestToPlayerQuests gives the quest to the player, rendered for
+// their current level.
+func insertQuestToPlayerQuests(store storage.Storage, quest *types.Quest, playerId int, effects types.SkillEffects) (*types.PlayerQuest, error) {
+	pq, err := newPlayerQuest(store, quest, playerId, effects)
+	if err != nil {
+		return nil, err
 	}
 
-	_, _, err = db.SupabaseClient.From("player_quests").Insert(map[string]any{
-		"start_at": utils.NowDate(),
-		"player":   id,
-		"quest":    quest.ID,
-		"status":   0,
-		"priority": quest.Priority,
-	}, false, "", "", "exact").Execute()
+	return store.CreatePlayerQuest(pq)
+}
+
+// newPlayerQuest is the player_quests row that gives the quest to the
+// player.
+func newPlayerQuest(store storage.Storage, quest *types.Quest, playerId int, effects types.SkillEffects) (*types.PlayerQuest, error) {
+	player, err := store.GetPlayer(playerId)
+	if err != nil {
+		return nil, err
+	}
 
-	return err
+	level, _ := Curve.Level(player.XP)
+	description, objectives := RenderQuest(quest, level)
+
+	startAt := clock()
+	return &types.PlayerQuest{
+		StartAt:  startAt,
+		PlayerID: playerId,
+		QuestID:  quest.ID,
+		Status:   types.QuestActive,
+		Priority: quest.Priority,
+		Cadence:  quest.Cadence,
+		Deadline: QuestDeadline(player, quest, startAt, effects),
+
+		Description: description,
+		Objectives:  objectives,
+	}, nil
 }
 
-func FinishQuest(playerId string, questId string) (*types.Skill, error) {
-	_, _, err := db.SupabaseClient.From("player_quests").Update(
-		map[string]any{"status": 1},
-		"",
-		"exact",
-	).Eq("status", "0").Eq("player", playerId).Eq("quest", questId).Execute()
+var (
+	ErrQuestNotAssigned = errors.New("this quest was never given to the player")
+	errNoQuests         = errors.New("no quests found")
+	ErrQuestNotActive   = errors.New("this quest is not active anymore")
+)
+
+// QuestReward is everything a player gets for finishing a quest.
+type QuestReward struct {
+	Skill         *types.Skill   `json:"skill"`
+	UpgradedSkill *SkillProgress `json:"upgraded_skill,omitempty"`
+	XP            int            `json:"xp"`
+	LeveledUp     bool           `json:"leveled_up"`
+	StatPoints    int            `json:"stat_points"`
+	Progress      PlayerProgress `json:"progress"`
+	// PenaltyCleared is set when the quest got the player out of the
+	// Penalty Zone, which gives no XP or skill.
+	PenaltyCleared bool `json:"penalty_cleared,omitempty"`
+	// Streak is set for main quests, StreakMilestone when the streak
+	// reached one. The milestone's XP is part of XP.
+	Streak          int              `json:"streak,omitempty"`
+	StreakMilestone *StreakMilestone `json:"streak_milestone,omitempty"`
+	// Chain is set when the quest is a step of a quest chain.
+	Chain *ChainProgress `json:"chain,omitempty"`
+	// Triggered are the emergency and hidden quests finishing this one
+	// handed out.
+	Triggered []*types.Quest `json:"triggered,omitempty"`
+}
 
+// FinishQuest completes the player's active quest and hands out its XP and
+// skill reward through store.Atomic, so a failure at any step leaves the
+// quest active and the player without the reward. On Supabase the writes
+// already made are reverted instead, and other requests can see them
+// until then.
+//
+// A nil Skill means the player already owns every skill the quest could
+// give, in which case one of them is upgraded instead.
+func FinishQuest(store storage.Storage, playerId string, questId string) (*QuestReward, error) {
+	pId, err := strconv.Atoi(playerId)
 	if err != nil {
 		return nil, err
 	}
 
-	quest, err := getQuestByID(questId)
+	qId, err := strconv.Atoi(questId)
 	if err != nil {
 		return nil, err
 	}
 
-	skill, err := RandomSkillLevelBased(playerId, quest.Priority)
+	var reward *QuestReward
+
+	err = store.Atomic(func(tx storage.Storage) error {
+		count, err := tx.UpdatePlayerQuestStatus(storage.PlayerQuestFilter{
+			PlayerID: pId,
+			QuestID:  qId,
+			Status:   []int{types.QuestActive},
+		}, types.QuestCompleted)
+
+		if err != nil {
+			return err
+		}
+
+		if count == 0 {
+			return questNotActiveError(tx, pId, qId)
+		}
+
+		quest, err := getQuestByID(tx, questId)
+		if err != nil {
+			return err
+		}
+
+		reward, err = grantQuestReward(tx, pId, quest)
+		return err
+	})
+
 	if err != nil {
 		return nil, err
 	}
 
-	err = GivePlayerNewSkill(playerId, skill)
+	return reward, nil
+}
+
+// grantQuestReward hands out the XP and skill reward of a quest the player
+// just completed. It is meant to run inside Atomic.
+func grantQuestReward(tx storage.Storage, playerId int, quest *types.Quest) (*QuestReward, error) {
+	reward := &QuestReward{}
+
+	if quest.Priority == types.PenaltyPriority {
+		player, err := tx.GetPlayer(playerId)
+		if err != nil {
+			return nil, err
+		}
+		reward.Progress = GetPlayerProgress(player)
+		reward.PenaltyCleared = true
+		return reward, nil
+	}
+
+	effects, err := PassiveEffects(tx, playerId)
 	if err != nil {
 		return nil, err
 	}
 
-	return skill, nil
+	reward.XP = QuestXPWithEffects(quest, effects)
+
+	if isMainQuest(quest) {
+		reward.Streak, reward.StreakMilestone, err = extendStreak(tx, playerId)
+		if err != nil {
+			return nil, err
+		}
+		if reward.StreakMilestone != nil {
+			reward.XP += reward.StreakMilestone.XP
+		}
+	}
+
+	if reward.Chain, err = advanceChain(tx, playerId, quest); err != nil {
+		return nil, err
+	}
+
+	player, levels, err := GrantXP(tx, playerId, reward.XP)
+	if err != nil {
+		return nil, err
+	}
+
+	reward.Progress = GetPlayerProgress(player)
+	reward.LeveledUp = levels > 0
+	reward.StatPoints = levels * StatPointsPerLevel
+
+	skill, err := RandomSkillLevelBased(tx, strconv.Itoa(playerId), quest.Priority)
+	switch {
+	case errors.Is(err, ErrNoSkillsLeft):
+		// a duplicate drop, it makes an owned skill stronger instead
+		reward.UpgradedSkill, err = UpgradeRandomSkill(tx, playerId, SkillXP*quest.Priority)
+		if err != nil {
+			return nil, err
+		}
+	case err != nil:
+		return nil, err
+	default:
+		reward.Skill = skill
+		if err = GivePlayerNewSkill(tx, strconv.Itoa(playerId), skill); err != nil {
+			return nil, err
+		}
+	}
+
+	// the new level, streak or skill may be what an event quest waits for
+	if reward.Triggered, err = fireTriggers(tx, playerId); err != nil {
+		return nil, err
+	}
+
+	return reward, nil
 }
 
-func TimeForQuest(main bool, playerId string) (*time.Time, error) {
-	var data []byte
-	var err error
+// questNotActiveError tells apart a quest the player never had from one
+// that was already finished, expired or abandoned.
+func questNotActiveError(store storage.Storage, playerId, questId int) error {
+	quests, err := store.ListPlayerQuests(storage.PlayerQuestFilter{
+		PlayerID: playerId,
+		QuestID:  questId,
+		Limit:    1,
+	})
+	if err != nil {
+		return err
+	}
 
-	if main {
-		data, _, err = db.SupabaseClient.From("player_quests").
-			Select("*", "exact", false).
-			Eq("player", playerId).
-			Eq("priority", "1").
-			Order("start_at", &postgrest.OrderOpts{Ascending: false}).
-			Limit(1, "").
-			Execute()
-	} else {
-		data, _, err = db.SupabaseClient.From("player_quests").
-			Select("*", "exact", false).
-			Eq("player", playerId).
-			Neq("priority", "1").
-			Order("start_at", &postgrest.OrderOpts{Ascending: false}).
-			Limit(1, "").
-			Execute()
+	if len(quests) == 0 {
+		return ErrQuestNotAssigned
 	}
 
+	return ErrQuestNotActive
+}
+
+func TimeForQuest(store storage.Storage, main bool, playerId string) (*time.Time, error) {
+	id, err := strconv.Atoi(playerId)
 	if err != nil {
 		return nil, err
 	}
 
-	var quests []types.PlayerQuest
-	err = json.Unmarshal(data, &quests)
+	kind := storage.SideQuests
+	if main {
+		kind = storage.MainQuests
+	}
+
+	quests, err := store.ListPlayerQuests(storage.PlayerQuestFilter{
+		PlayerID: id,
+		Kind:     kind,
+		Limit:    1,
+	})
+
 	if err != nil {
 		return nil, err
 	}
@@ -478,13 +696,66 @@ func TimeForQuest(main bool, playerId string) (*time.Time, error) {
 	return &quests[0].StartAt, nil
 }
 
-func UpdateOutdatedQuests() error {
-	outDatedTime := time.Now().Add(-time.Hour * 24)
-	_, _, err := db.SupabaseClient.From("player_quests").
-		Update(map[string]any{"status": 2}, "", "exact").
-		Eq("status", "0").
-		Lt("start_at", outDatedTime.UTC().Format("2006-01-02T15:04:05.999999Z")).
-		Execute()
+// UpdateOutdatedQuests expires every active quest past its deadline and
+// takes PunishmentXP, less the player's reductions, from its player, times
+// the weekly or monthly multiplier for those quests. Each
+// quest is handled in its own transaction so one failing player doesn't
+// hold back the rest. Missing a main quest, or a penalty quest, sends the
+// player to the Penalty Zone.
+func UpdateOutdatedQuests(store storage.Storage) error {
+	outdated, err := store.ListPlayerQuests(storage.PlayerQuestFilter{
+		Status:         []int{types.QuestActive},
+		DeadlineBefore: clock(),
+	})
+
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	for _, pq := range outdated {
+		errs = append(errs, expireQuest(store, pq))
+	}
+
+	return errors.Join(errs...)
+}
+
+func expireQuest(store storage.Storage, pq *types.PlayerQuest) error {
+	return store.Atomic(func(tx storage.Storage) error {
+		count, err := tx.UpdatePlayerQuestStatus(storage.PlayerQuestFilter{
+			ID:     pq.ID,
+			Status: []int{types.QuestActive},
+		}, types.QuestExpired)
+
+		// finished or expired by someone else in the meantime
+		if err != nil || count == 0 {
+			return err
+		}
+
+		effects, err := PassiveEffects(tx, pq.PlayerID)
+		if err != nil {
+			return err
+		}
+
+		punishment := cadencePunishment(pq.Cadence, effects)
+		if pq.Priority == types.PenaltyPriority {
+			streak, err := failedPenaltyStreak(tx, pq.PlayerID)
+			if err != nil {
+				return err
+			}
+			// the quest just expired, so it's part of the streak
+			punishment *= min(streak, MaxPenaltyEscalation)
+		}
+
+		if _, err = tx.AddPlayerXP(pq.PlayerID, -punishment); err != nil {
+			return err
+		}
+
+		// only the daily quests lead to the Penalty Zone
+		if !isDaily(pq.Cadence) || (pq.Priority != 1 && pq.Priority != types.PenaltyPriority) {
+			return nil
+		}
 
-	return err
+		return assignPenaltyQuest(tx, pq.PlayerID)
+	})
 }
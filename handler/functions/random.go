@@ -0,0 +1,117 @@
+package functions
+
+import (
+	cryptorand "crypto/rand"
+	"encoding/binary"
+	"fmt"
+	"hash/fnv"
+	"math/rand"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/MultiX0/solo_leveling_system/storage"
+	"github.com/MultiX0/solo_leveling_system/types"
+)
+
+// RollSource hands out the randomness behind quest, skill and trigger
+// rolls.
+type RollSource interface {
+	// Rand is the source of one roll of the player on the day that started
+	// at day. roll names what is rolled and how many of those came before
+	// it, e.g. "quest/2/1" for the second side quest roll of the day.
+	Rand(playerId int, day time.Time, roll string) *rand.Rand
+}
+
+// SeededRolls seeds every roll from Seed, the player, their day and the
+// roll, so the same roll always comes out the same way for the same
+// quest and skill state.
+type SeededRolls struct {
+	Seed int64
+}
+
+func (s SeededRolls) Rand(playerId int, day time.Time, roll string) *rand.Rand {
+	hash := fnv.New64a()
+	fmt.Fprintf(hash, "%d/%d/%s/%s", s.Seed, playerId, day.UTC().Format(time.RFC3339), roll)
+	return rand.New(rand.NewSource(int64(hash.Sum64())))
+}
+
+// Rolls is where every roll gets its randomness from. InitRolls gives it
+// its seed, swap it to pin the rolls down, e.g. in tests.
+var Rolls RollSource = SeededRolls{}
+
+// rollSeedSetting is the name the generated seed is stored under.
+const rollSeedSetting = "roll_seed"
+
+// InitRolls seeds Rolls from ROLL_SEED, or else from a seed generated on
+// the first start and stored with the rest of the data. A seed everyone
+// knows, like 0, would make every deployment roll the same.
+func InitRolls(store storage.Storage) error {
+	value := os.Getenv("ROLL_SEED")
+	if value == "" {
+		var random [8]byte
+		if _, err := cryptorand.Read(random[:]); err != nil {
+			return err
+		}
+
+		generated := strconv.FormatInt(int64(binary.BigEndian.Uint64(random[:])), 10)
+		stored, err := store.InitSetting(rollSeedSetting, generated)
+		if err != nil {
+			return err
+		}
+		value = stored
+	}
+
+	seed, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return fmt.Errorf("the roll seed must be a 64-bit integer: %w", err)
+	}
+
+	Rolls = SeededRolls{Seed: seed}
+	return nil
+}
+
+// questRand is the source of the player's next quest roll of the kind,
+// counted by the quests of the kind they got today.
+func questRand(store storage.Storage, kind storage.QuestKind, playerId int) (*rand.Rand, error) {
+	player, err := store.GetPlayer(playerId)
+	if err != nil {
+		return nil, err
+	}
+
+	dayStart := playerDayStart(player, clock())
+	given, err := store.ListPlayerQuests(storage.PlayerQuestFilter{
+		PlayerID:     playerId,
+		Kind:         kind,
+		StartedAfter: dayStart,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return Rolls.Rand(playerId, dayStart, fmt.Sprintf("quest/%d/%d", kind, len(given))), nil
+}
+
+// skillRand is the source of the skill roll for the player's latest
+// completed quest, counted by every quest they ever completed.
+func skillRand(store storage.Storage, player *types.Player, roll string) (*rand.Rand, error) {
+	completed, err := store.ListPlayerQuests(storage.PlayerQuestFilter{
+		PlayerID: player.ID,
+		Status:   []int{types.QuestCompleted},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	dayStart := playerDayStart(player, clock())
+	return Rolls.Rand(player.ID, dayStart, fmt.Sprintf("%s/%d", roll, len(completed))), nil
+}
+
+// triggerRand is the source of the chance roll of an event quest, one per
+// quest and hour of the player's day.
+func triggerRand(player *types.Player, quest *types.Quest) *rand.Rand {
+	now := clock()
+	dayStart := playerDayStart(player, now)
+	hour := int(now.Sub(dayStart).Hours())
+	return Rolls.Rand(player.ID, dayStart, fmt.Sprintf("trigger/%d/%d", quest.ID, hour))
+}
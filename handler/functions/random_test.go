@@ -0,0 +1,114 @@
+package functions
+
+import (
+	"fmt"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/MultiX0/solo_leveling_system/storage/memory"
+)
+
+func TestSeededRollsReplay(t *testing.T) {
+	day := time.Date(2026, 10, 17, 9, 0, 0, 0, time.UTC)
+	rolls := func(source RollSource, roll string) []int64 {
+		rng := source.Rand(7, day, roll)
+		var values []int64
+		for range 5 {
+			values = append(values, rng.Int63())
+		}
+		return values
+	}
+
+	first := rolls(SeededRolls{Seed: 42}, "quest/2/1")
+	same := func(values []int64) bool {
+		for i := range first {
+			if first[i] != values[i] {
+				return false
+			}
+		}
+		return true
+	}
+
+	if !same(rolls(SeededRolls{Seed: 42}, "quest/2/1")) {
+		t.Fatal("the same roll came out differently")
+	}
+	if same(rolls(SeededRolls{Seed: 43}, "quest/2/1")) {
+		t.Error("another seed rolled the same")
+	}
+	if same(rolls(SeededRolls{Seed: 42}, "quest/2/2")) {
+		t.Error("the next roll of the day came out the same")
+	}
+}
+
+func TestInitRollsKeepsGeneratedSeed(t *testing.T) {
+	defer func(rolls RollSource) { Rolls = rolls }(Rolls)
+	t.Setenv("ROLL_SEED", "")
+
+	store := memory.New()
+	if err := InitRolls(store); err != nil {
+		t.Fatal(err)
+	}
+	first := Rolls.(SeededRolls).Seed
+
+	// a restart rolls with the seed it stored
+	if err := InitRolls(store); err != nil {
+		t.Fatal(err)
+	}
+	if seed := Rolls.(SeededRolls).Seed; seed != first {
+		t.Fatalf("seed %d after a restart, want %d", seed, first)
+	}
+
+	if err := InitRolls(memory.New()); err != nil {
+		t.Fatal(err)
+	}
+	if Rolls.(SeededRolls).Seed == first {
+		t.Error("two stores got the same generated seed")
+	}
+
+	t.Setenv("ROLL_SEED", "12")
+	if err := InitRolls(store); err != nil {
+		t.Fatal(err)
+	}
+	if seed := Rolls.(SeededRolls).Seed; seed != 12 {
+		t.Errorf("seed %d with ROLL_SEED set, want 12", seed)
+	}
+}
+
+// playDay hands a new player their daily quests at the given time and
+// rolls the skill their main quest would drop, in a store of its own.
+func playDay(t *testing.T, at time.Time) string {
+	store, player := newTestStore(t, at)
+	id := strconv.Itoa(player.ID)
+
+	main, err, _ := GetMainQuest(store, id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sides, err, _ := GetSideQuests(store, id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	skill, err := RandomSkillLevelBased(store, id, main.Priority)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	draw := fmt.Sprintf("main %d, skill %d, side", main.ID, skill.ID)
+	for _, quest := range sides {
+		draw += fmt.Sprintf(" %d", quest.ID)
+	}
+	return draw
+}
+
+func TestDayReplays(t *testing.T) {
+	day := time.Date(2026, 10, 12, 10, 0, 0, 0, time.UTC)
+
+	first := playDay(t, day)
+	if again := playDay(t, day.Add(5*time.Hour)); again != first {
+		t.Errorf("the same day drew %q, then %q", first, again)
+	}
+	if next := playDay(t, day.Add(24*time.Hour)); next == first {
+		t.Errorf("the next day drew the same %q", first)
+	}
+}
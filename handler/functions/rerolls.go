@@ -0,0 +1,285 @@
+package functions
+
+import (
+	"errors"
+	"strconv"
+
+	"github.com/MultiX0/solo_leveling_system/storage"
+	"github.com/MultiX0/solo_leveling_system/types"
+)
+
+var (
+	ErrCannotAbandon   = errors.New("the daily main quest and Penalty Zone quests can't be abandoned")
+	ErrCannotReroll    = errors.New("only daily side quests can be rerolled")
+	ErrNoRerollsLeft   = errors.New("you have no rerolls left today")
+	ErrNotEnoughXP     = errors.New("you don't have enough xp to reroll")
+	ErrNothingToReroll = errors.New("there is no other side quest to reroll into")
+)
+
+var (
+	// MaxRerollsPerDay is how many side quests a player can reroll in one
+	// of their days.
+	MaxRerollsPerDay = 2
+	// RerollCostXP is what every reroll takes from the player.
+	RerollCostXP = 100
+)
+
+// AbandonedQuest is what giving up on a quest cost the player.
+type AbandonedQuest struct {
+	Punishment int            `json:"punishment"`
+	Progress   PlayerProgress `json:"progress"`
+}
+
+// Reroll is the side quest a rerolled one was swapped for.
+type Reroll struct {
+	Quest       *ActiveQuest   `json:"quest"`
+	Cost        int            `json:"cost"`
+	RerollsLeft int            `json:"rerolls_left"`
+	Progress    PlayerProgress `json:"progress"`
+}
+
+// activePlayerQuest is the player's active player_quests row for the quest.
+func activePlayerQuest(store storage.Storage, playerId, questId int) (*types.PlayerQuest, error) {
+	active, err := store.ListPlayerQuests(storage.PlayerQuestFilter{
+		PlayerID: playerId,
+		QuestID:  questId,
+		Status:   []int{types.QuestActive},
+		Limit:    1,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if len(active) == 0 {
+		return nil, questNotActiveError(store, playerId, questId)
+	}
+
+	return active[0], nil
+}
+
+// abandonPlayerQuest marks the row as abandoned, unless it stopped being
+// active in the meantime.
+func abandonPlayerQuest(tx storage.Storage, pq *types.PlayerQuest) error {
+	count, err := tx.UpdatePlayerQuestStatus(storage.PlayerQuestFilter{
+		ID:     pq.ID,
+		Status: []int{types.QuestActive},
+	}, types.QuestAbandoned)
+	if err != nil {
+		return err
+	}
+
+	if count == 0 {
+		return ErrQuestNotActive
+	}
+
+	return nil
+}
+
+// AbandonQuest gives up on an active quest. It costs the same as letting
+// the quest expire, but the player is done with it right away.
+func AbandonQuest(store storage.Storage, playerId string, questId string) (*AbandonedQuest, error) {
+	pId, err := strconv.Atoi(playerId)
+	if err != nil {
+		return nil, err
+	}
+
+	qId, err := strconv.Atoi(questId)
+	if err != nil {
+		return nil, err
+	}
+
+	var abandoned *AbandonedQuest
+
+	err = store.Atomic(func(tx storage.Storage) error {
+		pq, err := activePlayerQuest(tx, pId, qId)
+		if err != nil {
+			return err
+		}
+
+		// there is no way out of the daily training or the Penalty Zone
+		if pq.Priority == types.PenaltyPriority || (pq.Priority == 1 && isDaily(pq.Cadence)) {
+			return ErrCannotAbandon
+		}
+
+		if err = abandonPlayerQuest(tx, pq); err != nil {
+			return err
+		}
+
+		effects, err := PassiveEffects(tx, pId)
+		if err != nil {
+			return err
+		}
+
+		punishment := cadencePunishment(pq.Cadence, effects)
+		player, err := tx.AddPlayerXP(pId, -punishment)
+		if err != nil {
+			return err
+		}
+
+		abandoned = &AbandonedQuest{Punishment: punishment, Progress: GetPlayerProgress(player)}
+		return nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return abandoned, nil
+}
+
+// rerollsToday counts the side quests the player rerolled since their day
+// started.
+func rerollsToday(store storage.Storage, player *types.Player) (int, error) {
+	given, err := store.ListPlayerQuests(storage.PlayerQuestFilter{
+		PlayerID:     player.ID,
+		Kind:         storage.SideQuests,
+		StartedAfter: playerDayStart(player, clock()),
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	rerolls := 0
+	for _, pq := range given {
+		if pq.RerolledFrom != 0 {
+			rerolls++
+		}
+	}
+
+	return rerolls, nil
+}
+
+// rerollQuest picks a side quest to replace one of the active ones with.
+func rerollQuest(store storage.Storage, playerId int, active []*types.Quest) (*types.Quest, error) {
+	pool, err := questPool(store, storage.SideQuests, playerId)
+	if err != nil {
+		return nil, err
+	}
+
+	recent, err := recentQuests(store, playerId)
+	if err != nil {
+		return nil, err
+	}
+
+	rng, err := questRand(store, storage.SideQuests, playerId)
+	if err != nil {
+		return nil, err
+	}
+
+	quest := selectQuest(rng, pool, active, recent)
+	if quest == nil {
+		return nil, ErrNothingToReroll
+	}
+
+	return quest, nil
+}
+
+// RerollQuest swaps one of the player's active side quests for another
+// one, at the cost of RerollCostXP. A player can reroll MaxRerollsPerDay
+// times a day.
+func RerollQuest(store storage.Storage, playerId string, questId string) (*Reroll, error) {
+	pId, err := strconv.Atoi(playerId)
+	if err != nil {
+		return nil, err
+	}
+
+	qId, err := strconv.Atoi(questId)
+	if err != nil {
+		return nil, err
+	}
+
+	var reroll *Reroll
+	var quest *types.Quest
+
+	err = store.Atomic(func(tx storage.Storage) error {
+		pq, err := activePlayerQuest(tx, pId, qId)
+		if err != nil {
+			return err
+		}
+
+		if pq.Priority < 2 || !isDaily(pq.Cadence) {
+			return ErrCannotReroll
+		}
+
+		player, err := tx.GetPlayer(pId)
+		if err != nil {
+			return err
+		}
+
+		rerolls, err := rerollsToday(tx, player)
+		if err != nil {
+			return err
+		}
+		if rerolls >= MaxRerollsPerDay {
+			return ErrNoRerollsLeft
+		}
+		if player.XP < RerollCostXP {
+			return ErrNotEnoughXP
+		}
+
+		active, err := tx.ListPlayerQuests(storage.PlayerQuestFilter{
+			PlayerID: pId,
+			Status:   []int{types.QuestActive},
+			Kind:     storage.SideQuests,
+		})
+		if err != nil {
+			return err
+		}
+
+		activeQuests := []*types.Quest{}
+		for _, a := range active {
+			activeQuest, err := getQuestByID(tx, strconv.Itoa(a.QuestID))
+			if err != nil {
+				return err
+			}
+			activeQuests = append(activeQuests, activeQuest)
+		}
+
+		quest, err = rerollQuest(tx, pId, activeQuests)
+		if err != nil {
+			return err
+		}
+
+		if err = abandonPlayerQuest(tx, pq); err != nil {
+			return err
+		}
+
+		player, err = tx.AddPlayerXP(pId, -RerollCostXP)
+		if err != nil {
+			return err
+		}
+
+		effects, err := PassiveEffects(tx, pId)
+		if err != nil {
+			return err
+		}
+
+		newPQ, err := newPlayerQuest(tx, quest, pId, effects)
+		if err != nil {
+			return err
+		}
+		newPQ.RerolledFrom = pq.ID
+		if _, err = tx.CreatePlayerQuest(newPQ); err != nil {
+			return err
+		}
+
+		reroll = &Reroll{
+			Cost:        RerollCostXP,
+			RerollsLeft: MaxRerollsPerDay - rerolls - 1,
+			Progress:    GetPlayerProgress(player),
+		}
+		return nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	withProgress, err := WithProgress(store, playerId, quest)
+	if err != nil {
+		return nil, err
+	}
+	reroll.Quest = withProgress[0]
+
+	return reroll, nil
+}
@@ -0,0 +1,124 @@
+package functions
+
+import (
+	"errors"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/MultiX0/solo_leveling_system/storage"
+	"github.com/MultiX0/solo_leveling_system/types"
+)
+
+func TestRerollQuest(t *testing.T) {
+	// 19:00 in Tokyo, where the player's day started at 06:00, 21:00 UTC
+	// the day before
+	start := time.Date(2026, 10, 12, 10, 0, 0, 0, time.UTC)
+	store, player := newTestStore(t, start)
+	id := strconv.Itoa(player.ID)
+
+	if err := store.UpdatePlayerSchedule(player.ID, types.Schedule{TimeZone: "Asia/Tokyo", ResetHour: 6}); err != nil {
+		t.Fatal(err)
+	}
+
+	sideQuests, err, _ := GetSideQuests(store, id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sideQuests) < 2 {
+		t.Fatalf("%d side quests, want at least 2", len(sideQuests))
+	}
+
+	if _, err = RerollQuest(store, id, strconv.Itoa(sideQuests[0].ID)); !errors.Is(err, ErrNotEnoughXP) {
+		t.Errorf("rerolling without xp: %v, want %v", err, ErrNotEnoughXP)
+	}
+
+	if _, err = store.AddPlayerXP(player.ID, 3*RerollCostXP); err != nil {
+		t.Fatal(err)
+	}
+
+	// rerolls the first side quest, then what it was rerolled into
+	questId := sideQuests[0].ID
+	for i := range MaxRerollsPerDay {
+		old, err := activePlayerQuest(store, player.ID, questId)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		reroll, err := RerollQuest(store, id, strconv.Itoa(questId))
+		if err != nil {
+			t.Fatalf("reroll %d: %v", i+1, err)
+		}
+		if want := MaxRerollsPerDay - i - 1; reroll.RerollsLeft != want {
+			t.Errorf("reroll %d: %d rerolls left, want %d", i+1, reroll.RerollsLeft, want)
+		}
+
+		rerolled, err := activePlayerQuest(store, player.ID, reroll.Quest.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if rerolled.RerolledFrom != old.ID {
+			t.Errorf("reroll %d: rerolled from %d, want %d", i+1, rerolled.RerolledFrom, old.ID)
+		}
+
+		given, err := store.ListPlayerQuests(storage.PlayerQuestFilter{PlayerID: player.ID, Kind: storage.SideQuests})
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, pq := range given {
+			if pq.ID == old.ID && pq.Status != types.QuestAbandoned {
+				t.Errorf("reroll %d: the rerolled quest has status %d, want %d", i+1, pq.Status, types.QuestAbandoned)
+			}
+		}
+
+		questId = reroll.Quest.ID
+	}
+
+	player, err = store.GetPlayer(player.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := (3 - MaxRerollsPerDay) * RerollCostXP; player.XP != want {
+		t.Errorf("%d xp left after %d rerolls, want %d", player.XP, MaxRerollsPerDay, want)
+	}
+
+	last := strconv.Itoa(sideQuests[1].ID)
+	if _, err = RerollQuest(store, id, last); !errors.Is(err, ErrNoRerollsLeft) {
+		t.Errorf("rerolling once more: %v, want %v", err, ErrNoRerollsLeft)
+	}
+
+	// a reroll from the day before, given after today's ones
+	if _, err = store.CreatePlayerQuest(&types.PlayerQuest{
+		PlayerID:     player.ID,
+		QuestID:      sideQuests[1].ID,
+		Status:       types.QuestAbandoned,
+		Priority:     sideQuests[1].Priority,
+		Cadence:      types.CadenceDaily,
+		StartAt:      start.Add(-20 * time.Hour),
+		Deadline:     start.Add(-13 * time.Hour),
+		RerolledFrom: 1,
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		at   time.Time
+		want int
+	}{
+		{"right after the rerolls", start, MaxRerollsPerDay},
+		{"a minute before the reset", time.Date(2026, 10, 12, 20, 59, 0, 0, time.UTC), MaxRerollsPerDay},
+		{"at the reset", time.Date(2026, 10, 12, 21, 0, 0, 0, time.UTC), 0},
+	}
+
+	for _, test := range tests {
+		clock = func() time.Time { return test.at }
+		if got, err := rerollsToday(store, player); err != nil || got != test.want {
+			t.Errorf("%s: %d rerolls today (%v), want %d", test.name, got, err, test.want)
+		}
+	}
+
+	if _, err = RerollQuest(store, id, last); err != nil {
+		t.Errorf("rerolling after the reset: %v", err)
+	}
+}
@@ -0,0 +1,176 @@
+package functions
+
+import (
+	"errors"
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/MultiX0/solo_leveling_system/storage"
+	"github.com/MultiX0/solo_leveling_system/types"
+	"github.com/MultiX0/solo_leveling_system/utils"
+)
+
+var (
+	ErrInvalidTimeZone  = errors.New("the time zone must be an IANA name such as Europe/Berlin")
+	ErrInvalidResetHour = errors.New("the reset hour must be between 0 and 23")
+)
+
+// clock is the time days, deadlines and rolls are measured against. Tests
+// set it to the day they play out.
+var clock = time.Now
+
+// loadLocation is the time zone named timeZone, UTC when it is unknown.
+func loadLocation(player *types.Player, timeZone string) *time.Location {
+	if timeZone == "" {
+		return time.UTC
+	}
+
+	loc, err := time.LoadLocation(timeZone)
+	if err != nil {
+		log.Printf("player %d has an unknown time zone %q, using UTC", player.ID, timeZone)
+		return time.UTC
+	}
+
+	return loc
+}
+
+// PlayerLocation is the player's time zone, UTC when it is unknown.
+func PlayerLocation(player *types.Player) *time.Location {
+	return loadLocation(player, player.TimeZone)
+}
+
+// scheduleAt is the time zone and reset hour the player's days follow at
+// t, the previous ones until a change takes over.
+func scheduleAt(player *types.Player, t time.Time) (*time.Location, int) {
+	if from := player.ScheduleFrom; from != nil && t.Before(*from) {
+		return loadLocation(player, player.PreviousTimeZone), player.PreviousResetHour
+	}
+	return PlayerLocation(player), player.ResetHour
+}
+
+// lastDayBeforeChange is when the last day of the previous schedule
+// started, as long as t is before the new one takes over. That day lasts
+// until then, so changing the schedule never makes a day shorter.
+func lastDayBeforeChange(player *types.Player, t time.Time) (time.Time, bool) {
+	from := player.ScheduleFrom
+	if from == nil || !t.Before(*from) {
+		return time.Time{}, false
+	}
+
+	loc, hour := scheduleAt(player, t)
+	return utils.DayStart(from.Add(-24*time.Hour), loc, hour), true
+}
+
+// playerDayStart is when the player's day that t falls on started.
+func playerDayStart(player *types.Player, t time.Time) time.Time {
+	loc, hour := scheduleAt(player, t)
+	start := utils.DayStart(t, loc, hour)
+	if last, ok := lastDayBeforeChange(player, t); ok && start.After(last) {
+		return last
+	}
+	return start
+}
+
+// playerNextReset is when the player's daily quests roll over after t.
+func playerNextReset(player *types.Player, t time.Time) time.Time {
+	if last, ok := lastDayBeforeChange(player, t); ok && !t.Before(last) {
+		return *player.ScheduleFrom
+	}
+	loc, hour := scheduleAt(player, t)
+	return utils.NextReset(t, loc, hour)
+}
+
+// periodBounds is the player's day, week or month that t falls on. Weeks
+// start on Monday and months on the 1st, at the player's reset hour.
+func periodBounds(player *types.Player, cadence string, t time.Time) (time.Time, time.Time) {
+	loc, hour := scheduleAt(player, t)
+	year, month, day := playerDayStart(player, t).In(loc).Date()
+
+	switch cadence {
+	case types.CadenceWeekly:
+		sinceMonday := (int(time.Date(year, month, day, 0, 0, 0, 0, loc).Weekday()) + 6) % 7
+		start := time.Date(year, month, day-sinceMonday, hour, 0, 0, 0, loc)
+		return start, time.Date(year, month, day-sinceMonday+7, hour, 0, 0, 0, loc)
+	case types.CadenceMonthly:
+		return time.Date(year, month, 1, hour, 0, 0, 0, loc), time.Date(year, month+1, 1, hour, 0, 0, 0, loc)
+	}
+
+	return playerDayStart(player, t), playerNextReset(player, t)
+}
+
+// PlayerNextReset is when the day that t falls on ends for the player.
+func PlayerNextReset(store storage.Storage, playerId string, t time.Time) (time.Time, error) {
+	id, err := strconv.Atoi(playerId)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	player, err := store.GetPlayer(id)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	return playerNextReset(player, t), nil
+}
+
+// SetPlayerSchedule makes the player's daily quests roll over at
+// resetHour o'clock in timeZone. The change takes over at the first such
+// reset a full day after today started, until then today goes on, so a
+// change can make a day longer but never hand out a second day's quests
+// early. Quests already given keep their deadline.
+func SetPlayerSchedule(store storage.Storage, playerId string, timeZone string, resetHour int) (*types.Player, error) {
+	id, err := strconv.Atoi(playerId)
+	if err != nil {
+		return nil, err
+	}
+
+	if resetHour < 0 || resetHour > 23 {
+		return nil, ErrInvalidResetHour
+	}
+
+	// "Local" would be the server's time zone, not the player's
+	if timeZone == "" || timeZone == "Local" {
+		return nil, ErrInvalidTimeZone
+	}
+	loc, err := time.LoadLocation(timeZone)
+	if err != nil {
+		return nil, ErrInvalidTimeZone
+	}
+
+	player, err := store.GetPlayer(id)
+	if err != nil {
+		return nil, err
+	}
+
+	// today keeps the schedule it started with, even when another change
+	// is still waiting to take over
+	now := clock()
+	todayZone, todayHour := player.TimeZone, player.ResetHour
+	_, pending := lastDayBeforeChange(player, now)
+	if pending {
+		todayZone, todayHour = player.PreviousTimeZone, player.PreviousResetHour
+	}
+
+	schedule := types.Schedule{TimeZone: timeZone, ResetHour: resetHour}
+	if timeZone == todayZone && resetHour == todayHour {
+		if !pending {
+			return player, nil
+		}
+		// calls off the change that was waiting
+	} else {
+		dayEnd := playerDayStart(player, now).Add(24 * time.Hour)
+		from := utils.DayStart(dayEnd, loc, resetHour)
+		if from.Before(dayEnd) {
+			from = utils.NextReset(dayEnd, loc, resetHour)
+		}
+		schedule.ScheduleFrom = &from
+		schedule.PreviousTimeZone, schedule.PreviousResetHour = todayZone, todayHour
+	}
+
+	if err = store.UpdatePlayerSchedule(id, schedule); err != nil {
+		return nil, err
+	}
+
+	return store.GetPlayer(id)
+}
@@ -0,0 +1,57 @@
+package functions
+
+import (
+	"testing"
+	"time"
+
+	"github.com/MultiX0/solo_leveling_system/types"
+)
+
+func TestScheduleChangeNeverShortensADay(t *testing.T) {
+	// changed at 09:30 from a midnight reset to a 09:00 one
+	from := time.Date(2026, 10, 18, 9, 0, 0, 0, time.UTC)
+	player := &types.Player{Schedule: types.Schedule{
+		TimeZone:          "UTC",
+		ResetHour:         9,
+		ScheduleFrom:      &from,
+		PreviousTimeZone:  "UTC",
+		PreviousResetHour: 0,
+	}}
+
+	tests := []struct {
+		at        time.Time
+		dayStart  time.Time
+		nextReset time.Time
+	}{
+		{
+			at:        time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC),
+			dayStart:  time.Date(2026, 10, 16, 0, 0, 0, 0, time.UTC),
+			nextReset: time.Date(2026, 10, 17, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			at:        time.Date(2026, 10, 17, 9, 30, 0, 0, time.UTC),
+			dayStart:  time.Date(2026, 10, 17, 0, 0, 0, 0, time.UTC),
+			nextReset: from,
+		},
+		// the old midnight reset is skipped
+		{
+			at:        time.Date(2026, 10, 18, 1, 0, 0, 0, time.UTC),
+			dayStart:  time.Date(2026, 10, 17, 0, 0, 0, 0, time.UTC),
+			nextReset: from,
+		},
+		{
+			at:        time.Date(2026, 10, 18, 9, 0, 0, 0, time.UTC),
+			dayStart:  from,
+			nextReset: time.Date(2026, 10, 19, 9, 0, 0, 0, time.UTC),
+		},
+	}
+
+	for _, test := range tests {
+		if got := playerDayStart(player, test.at); !got.Equal(test.dayStart) {
+			t.Errorf("playerDayStart(%v) = %v, want %v", test.at, got, test.dayStart)
+		}
+		if got := playerNextReset(player, test.at); !got.Equal(test.nextReset) {
+			t.Errorf("playerNextReset(%v) = %v, want %v", test.at, got, test.nextReset)
+		}
+	}
+}
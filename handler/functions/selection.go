@@ -0,0 +1,94 @@
+package functions
+
+import (
+	"math/rand"
+	"time"
+
+	"github.com/MultiX0/solo_leveling_system/storage"
+	"github.com/MultiX0/solo_leveling_system/types"
+)
+
+// QuestCooldown is how long a quest isn't given to the same player again,
+// as long as their pool has other quests of the kind.
+var QuestCooldown = 3 * 24 * time.Hour
+
+func questWeight(quest *types.Quest) int {
+	if quest.Weight <= 0 {
+		return types.DefaultQuestWeight
+	}
+	return quest.Weight
+}
+
+// recentQuests are the quests the player was given within QuestCooldown.
+func recentQuests(store storage.Storage, playerId int) (map[int]bool, error) {
+	given, err := store.ListPlayerQuests(storage.PlayerQuestFilter{
+		PlayerID:     playerId,
+		StartedAfter: clock().Add(-QuestCooldown),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	recent := map[int]bool{}
+	for _, pq := range given {
+		recent[pq.QuestID] = true
+	}
+
+	return recent, nil
+}
+
+// selectQuest narrows the pool down to the quests that weren't picked
+// yet, then to the ones off cooldown and then to the categories not picked
+// yet, skipping any step that would leave nothing, and picks one of what
+// is left by weight. It returns nil when every quest was picked.
+func selectQuest(rng *rand.Rand, pool []types.Quest, picked []*types.Quest, recent map[int]bool) *types.Quest {
+	pickedIDs := map[int]bool{}
+	pickedCategories := map[string]bool{}
+	for _, quest := range picked {
+		pickedIDs[quest.ID] = true
+		if quest.Category != "" {
+			pickedCategories[quest.Category] = true
+		}
+	}
+
+	var candidates []*types.Quest
+	for i := range pool {
+		if !pickedIDs[pool[i].ID] {
+			candidates = append(candidates, &pool[i])
+		}
+	}
+	if len(candidates) == 0 {
+		return nil
+	}
+
+	preferences := []func(quest *types.Quest) bool{
+		func(quest *types.Quest) bool { return !recent[quest.ID] },
+		func(quest *types.Quest) bool { return !pickedCategories[quest.Category] },
+	}
+	for _, prefer := range preferences {
+		var preferred []*types.Quest
+		for _, quest := range candidates {
+			if prefer(quest) {
+				preferred = append(preferred, quest)
+			}
+		}
+		if len(preferred) > 0 {
+			candidates = preferred
+		}
+	}
+
+	total := 0
+	for _, quest := range candidates {
+		total += questWeight(quest)
+	}
+
+	roll := rng.Intn(total)
+	for _, quest := range candidates {
+		roll -= questWeight(quest)
+		if roll < 0 {
+			return quest
+		}
+	}
+
+	return candidates[len(candidates)-1]
+}
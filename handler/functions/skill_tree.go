@@ -0,0 +1,125 @@
+package functions
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/MultiX0/solo_leveling_system/storage"
+	"github.com/MultiX0/solo_leveling_system/types"
+)
+
+const (
+	SkillOwned      = "owned"
+	SkillUnlockable = "unlockable"
+	SkillLocked     = "locked"
+)
+
+// SkillNode is one skill of the tree as seen by a player.
+type SkillNode struct {
+	Skill *types.Skill `json:"skill"`
+	// State is owned, unlockable (every prerequisite met) or locked.
+	State string `json:"state"`
+	// Missing lists the prerequisites a locked skill is still waiting on.
+	Missing []string `json:"missing"`
+	// Unlocks are the skills that list this one as a prerequisite.
+	Unlocks []string `json:"unlocks"`
+}
+
+// missingRequirements describes every prerequisite of skill the player
+// doesn't meet yet.
+func missingRequirements(skill *types.Skill, player *types.Player, owned map[string]bool) []string {
+	missing := []string{}
+	req := skill.Requires
+	if req == nil {
+		return missing
+	}
+
+	for _, name := range req.Skills {
+		if !owned[name] {
+			missing = append(missing, "requires skill "+name)
+		}
+	}
+
+	if level, _ := Curve.Level(player.XP); level < req.Level {
+		missing = append(missing, fmt.Sprintf("requires level %d", req.Level))
+	}
+
+	if req.Stats != nil {
+		stats := []struct {
+			name      string
+			have, min int
+		}{
+			{"strength", player.Strength, req.Stats.Strength},
+			{"agility", player.Agility, req.Stats.Agility},
+			{"sense", player.Sense, req.Stats.Sense},
+			{"vitality", player.Vitality, req.Stats.Vitality},
+			{"intelligence", player.Intelligence, req.Stats.Intelligence},
+		}
+		for _, stat := range stats {
+			if stat.have < stat.min {
+				missing = append(missing, fmt.Sprintf("requires %s %d", stat.name, stat.min))
+			}
+		}
+	}
+
+	return missing
+}
+
+// GetSkillTree returns the whole skill catalogue in catalogue order, each
+// node marked for the given player.
+func GetSkillTree(store storage.Storage, playerId string) ([]*SkillNode, error) {
+	id, err := strconv.Atoi(playerId)
+	if err != nil {
+		return nil, err
+	}
+
+	player, err := store.GetPlayer(id)
+	if err != nil {
+		return nil, err
+	}
+
+	skills, err := store.ListSkills()
+	if err != nil {
+		return nil, err
+	}
+
+	owned, err := ownedSkillNames(store, id, skills)
+	if err != nil {
+		return nil, err
+	}
+
+	unlocks := map[string][]string{}
+	for _, skill := range skills {
+		if skill.Requires == nil {
+			continue
+		}
+		for _, name := range skill.Requires.Skills {
+			unlocks[name] = append(unlocks[name], skill.Name)
+		}
+	}
+
+	tree := []*SkillNode{}
+	for _, skill := range skills {
+		node := &SkillNode{
+			Skill:   skill,
+			State:   SkillLocked,
+			Missing: missingRequirements(skill, player, owned),
+			Unlocks: unlocks[skill.Name],
+		}
+		if node.Unlocks == nil {
+			node.Unlocks = []string{}
+		}
+
+		switch {
+		case owned[skill.Name]:
+			node.State = SkillOwned
+			node.Missing = []string{}
+		case len(node.Missing) == 0:
+			node.State = SkillUnlockable
+		}
+
+		tree = append(tree, node)
+	}
+
+	return tree, nil
+}
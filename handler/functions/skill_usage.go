@@ -0,0 +1,182 @@
+package functions
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/MultiX0/solo_leveling_system/storage"
+	"github.com/MultiX0/solo_leveling_system/types"
+)
+
+var (
+	ErrSkillNotOwned   = errors.New("the player doesn't have this skill")
+	ErrSkillNotActive  = errors.New("passive skills can't be used")
+	ErrSkillOnCooldown = errors.New("the skill is on cooldown")
+	ErrNotEnoughMana   = errors.New("not enough mana")
+)
+
+func MaxMana(player *types.Player) int {
+	return player.Intelligence * ManaPerIntelligence
+}
+
+// CurrentMana is the player's stored mana plus what regenerated since it
+// was stored, capped at MaxMana.
+func CurrentMana(player *types.Player, now time.Time) int {
+	maxMana := MaxMana(player)
+	if player.ManaUpdatedAt == nil {
+		return maxMana
+	}
+
+	regen := int(now.Sub(*player.ManaUpdatedAt).Minutes() * ManaRegenPerMinute)
+	return max(min(player.Mana+regen, maxMana), 0)
+}
+
+// CooldownLeft is how long until an active skill last used at lastUsed can
+// be used again, zero when it is ready.
+func CooldownLeft(skill *types.Skill, lastUsed time.Time, now time.Time) time.Duration {
+	readyAt := lastUsed.Add(time.Duration(skill.Cooldown) * time.Second)
+	if !readyAt.After(now) {
+		return 0
+	}
+	return readyAt.Sub(now)
+}
+
+// skillCooldownLeft looks up the player's last use of the skill.
+func skillCooldownLeft(store storage.Storage, playerId int, skill *types.Skill, now time.Time) (time.Duration, error) {
+	if skill.Type != types.SkillActive {
+		return 0, nil
+	}
+
+	last, err := store.LastSkillUsage(playerId, skill.ID)
+	if errors.Is(err, storage.ErrNotFound) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	return CooldownLeft(skill, last.UsedAt, now), nil
+}
+
+// SkillUse is the outcome of using an active skill.
+type SkillUse struct {
+	Skill   *SkillProgress `json:"skill"`
+	UsedAt  time.Time      `json:"used_at"`
+	ReadyAt time.Time      `json:"ready_at"`
+	Mana    int            `json:"mana"`
+	MaxMana int            `json:"max_mana"`
+}
+
+// errManaChanged means another use of a skill spent the player's mana
+// first, so the checks have to be made again.
+var errManaChanged = errors.New("the player's mana changed while the skill was used")
+
+// maxSkillUseRetries is how often UseSkill checks again after another use
+// got in first.
+const maxSkillUseRetries = 5
+
+// UseSkill spends the skill's mana cost, starts its cooldown and logs the
+// usage, all in one transaction. The mana is only spent if nothing else
+// spent it since it was read, so two uses at once can't both pass the
+// checks even where the transaction isn't a real one, like on Supabase.
+func UseSkill(store storage.Storage, playerId, skillId string) (*SkillUse, error) {
+	pId, err := strconv.Atoi(playerId)
+	if err != nil {
+		return nil, err
+	}
+
+	sId, err := strconv.Atoi(skillId)
+	if err != nil {
+		return nil, err
+	}
+
+	for range maxSkillUseRetries {
+		use, err := useSkill(store, pId, sId)
+		if !errors.Is(err, errManaChanged) {
+			return use, err
+		}
+	}
+
+	return nil, storage.ErrConflict
+}
+
+func useSkill(store storage.Storage, pId, sId int) (*SkillUse, error) {
+	var use *SkillUse
+	err := store.Atomic(func(tx storage.Storage) error {
+		ps, err := tx.GetPlayerSkill(pId, sId)
+		if errors.Is(err, storage.ErrNotFound) {
+			return ErrSkillNotOwned
+		}
+		if err != nil {
+			return err
+		}
+
+		skill, err := tx.GetSkill(sId)
+		if err != nil {
+			return err
+		}
+
+		if skill.Type != types.SkillActive {
+			return ErrSkillNotActive
+		}
+
+		// read before the cooldown, every use moves the mana on, so a use
+		// the cooldown check misses makes spending it fail
+		player, err := tx.GetPlayer(pId)
+		if err != nil {
+			return err
+		}
+
+		now := clock().UTC()
+		left, err := skillCooldownLeft(tx, pId, skill, now)
+		if err != nil {
+			return err
+		}
+		if left > 0 {
+			return fmt.Errorf("%w, %s left", ErrSkillOnCooldown, left.Round(time.Second))
+		}
+
+		mana := CurrentMana(player, now)
+		if mana < skill.ManaCost {
+			return fmt.Errorf("%w, %s needs %d and you have %d", ErrNotEnoughMana, skill.Name, skill.ManaCost, mana)
+		}
+
+		usage, err := tx.CreateSkillUsage(&types.SkillUsage{
+			PlayerID: pId,
+			SkillID:  sId,
+			UsedAt:   now,
+			ManaCost: skill.ManaCost,
+		})
+		if err != nil {
+			return err
+		}
+
+		mana -= skill.ManaCost
+		spent, err := tx.SpendPlayerMana(pId, player.Mana, player.ManaUpdatedAt, mana, now)
+		if err != nil {
+			return err
+		}
+		if !spent {
+			return errManaChanged
+		}
+
+		progress := newSkillProgress(skill, ps)
+		progress.CooldownLeft = skill.Cooldown
+
+		use = &SkillUse{
+			Skill:   progress,
+			UsedAt:  usage.UsedAt,
+			ReadyAt: usage.UsedAt.Add(time.Duration(skill.Cooldown) * time.Second),
+			Mana:    mana,
+			MaxMana: MaxMana(player),
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return use, nil
+}
@@ -0,0 +1,118 @@
+package functions
+
+import (
+	"errors"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/MultiX0/solo_leveling_system/storage/memory"
+	"github.com/MultiX0/solo_leveling_system/types"
+)
+
+func TestCurrentMana(t *testing.T) {
+	now := time.Date(2026, 10, 12, 10, 0, 0, 0, time.UTC)
+	minutesAgo := func(minutes int) *time.Time {
+		at := now.Add(-time.Duration(minutes) * time.Minute)
+		return &at
+	}
+
+	// 10 intelligence gives 100 max mana, 1 comes back every minute
+	tests := []struct {
+		name      string
+		mana      int
+		updatedAt *time.Time
+		want      int
+	}{
+		{"never spent any", 0, nil, 100},
+		{"just spent", 40, minutesAgo(0), 40},
+		{"regenerating", 40, minutesAgo(25), 65},
+		{"back to full", 40, minutesAgo(60), 100},
+		{"long after", 40, minutesAgo(100000), 100},
+	}
+
+	for _, test := range tests {
+		player := &types.Player{
+			Stats:         types.Stats{Intelligence: 10},
+			Mana:          test.mana,
+			ManaUpdatedAt: test.updatedAt,
+		}
+		if got := CurrentMana(player, now); got != test.want {
+			t.Errorf("%s: %d mana, want %d", test.name, got, test.want)
+		}
+	}
+}
+
+// giveSkill gives the player the skill with the given name and returns its
+// ID.
+func giveSkill(t *testing.T, store *memory.Store, player *types.Player, name string) string {
+	t.Helper()
+
+	skill, err := store.GetSkillByName(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = store.CreatePlayerSkill(&types.PlayerSkills{PlayerID: player.ID, SkillID: skill.ID}); err != nil {
+		t.Fatal(err)
+	}
+
+	return strconv.Itoa(skill.ID)
+}
+
+func TestUseSkill(t *testing.T) {
+	start := time.Date(2026, 10, 12, 10, 0, 0, 0, time.UTC)
+	store, player := newTestStore(t, start)
+	id := strconv.Itoa(player.ID)
+
+	// 25 mana and a 10 minute cooldown
+	healing := giveSkill(t, store, player, "Healing Light")
+	// 80 mana
+	blessing := giveSkill(t, store, player, "Divine Blessing")
+	passive := giveSkill(t, store, player, "Iron Will")
+
+	use, err := UseSkill(store, id, healing)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if use.Mana != 75 || !use.ReadyAt.Equal(start.Add(10*time.Minute)) {
+		t.Errorf("%d mana left and ready at %v, want 75 at %v", use.Mana, use.ReadyAt, start.Add(10*time.Minute))
+	}
+
+	clock = func() time.Time { return start.Add(9 * time.Minute) }
+	if _, err = UseSkill(store, id, healing); !errors.Is(err, ErrSkillOnCooldown) {
+		t.Errorf("using it during the cooldown: %v, want %v", err, ErrSkillOnCooldown)
+	}
+
+	// 75 plus 10 minutes of regen
+	clock = func() time.Time { return start.Add(10 * time.Minute) }
+	if use, err = UseSkill(store, id, healing); err != nil {
+		t.Fatalf("using it once the cooldown is over: %v", err)
+	}
+	if use.Mana != 60 {
+		t.Errorf("%d mana left, want 60", use.Mana)
+	}
+
+	if _, err = UseSkill(store, id, blessing); !errors.Is(err, ErrNotEnoughMana) {
+		t.Errorf("using a skill that costs more than is left: %v, want %v", err, ErrNotEnoughMana)
+	}
+
+	player, err = store.GetPlayer(player.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if player.Mana != 60 {
+		t.Errorf("a refused use left %d mana, want 60", player.Mana)
+	}
+
+	if _, err = UseSkill(store, id, passive); !errors.Is(err, ErrSkillNotActive) {
+		t.Errorf("using a passive skill: %v, want %v", err, ErrSkillNotActive)
+	}
+
+	shadowStep, err := store.GetSkillByName("Shadow Step")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = UseSkill(store, id, strconv.Itoa(shadowStep.ID)); !errors.Is(err, ErrSkillNotOwned) {
+		t.Errorf("using a skill the player doesn't have: %v, want %v", err, ErrSkillNotOwned)
+	}
+}
@@ -1,25 +1,23 @@
 package functions
 
 import (
-	"encoding/json"
+	"errors"
 	"fmt"
-	"math/rand"
+	"math"
 	"strconv"
+	"time"
 
-	"github.com/MultiX0/solo_leveling_system/db"
+	"github.com/MultiX0/solo_leveling_system/storage"
 	"github.com/MultiX0/solo_leveling_system/types"
 )
 
-func GetPlayerSkills(id string) ([]*types.Skill, error) {
-
-	data, _, err := db.SupabaseClient.From("player_skills").Select("*", "", false).Eq("player", id).Execute()
+func GetPlayerSkills(store storage.Storage, id string) ([]*types.Skill, error) {
+	playerId, err := strconv.Atoi(id)
 	if err != nil {
 		return nil, err
 	}
 
-	var skillsData []*types.PlayerSkills
-	err = json.Unmarshal(data, &skillsData)
-
+	skillsData, err := store.ListPlayerSkills(playerId)
 	if err != nil {
 		return nil, err
 	}
@@ -28,7 +26,7 @@ func GetPlayerSkills(id string) ([]*types.Skill, error) {
 
 	for _, s := range skillsData {
 
-		recivedSkill, err := getSkillByID(strconv.Itoa(s.SkillID))
+		recivedSkill, err := store.GetSkill(s.SkillID)
 		if err != nil {
 			return nil, err
 		}
@@ -40,85 +38,219 @@ func GetPlayerSkills(id string) ([]*types.Skill, error) {
 
 }
 
-func getSkillByID(id string) (*types.Skill, error) {
-	data, _, err := db.SupabaseClient.From("skills").Select("*", "", false).Eq("id", id).Single().Execute()
+var ErrNoSkillsLeft = errors.New("you already have all the skills")
+
+// RandomSkillLevelBased picks a random skill of the given level that the
+// player doesn't own yet and whose prerequisites are met, moving up a
+// level whenever nothing of the current one is unlockable. Once the higher
+// levels are exhausted it falls back to the levels below.
+func RandomSkillLevelBased(store storage.Storage, playerId string, level int) (*types.Skill, error) {
+	id, err := strconv.Atoi(playerId)
 	if err != nil {
 		return nil, err
 	}
 
-	var skill *types.Skill
-	err = json.Unmarshal(data, &skill)
+	player, err := store.GetPlayer(id)
+	if err != nil {
+		return nil, err
+	}
 
+	skills, err := store.ListSkills()
 	if err != nil {
 		return nil, err
 	}
 
-	return skill, nil
-}
+	owned, err := ownedSkillNames(store, id, skills)
+	if err != nil {
+		return nil, err
+	}
 
-func RandomSkillLevelBased(playerId string, level int) (*types.Skill, error) {
+	rng, err := skillRand(store, player, "skill")
+	if err != nil {
+		return nil, err
+	}
 
-	if level > 100 {
-		return nil, fmt.Errorf("you already have all the skills")
+	byLevel := map[int][]*types.Skill{}
+	for _, skill := range skills {
+		byLevel[skill.Level] = append(byLevel[skill.Level], skill)
 	}
 
-	levelStr := strconv.Itoa(level)
-	data, _, err := db.SupabaseClient.From("skills").Select("*", "", false).Eq("level", levelStr).Execute()
+	levels := []int{}
+	for l := level; l <= 100; l++ {
+		levels = append(levels, l)
+	}
+	for l := level - 1; l >= 1; l-- {
+		levels = append(levels, l)
+	}
+
+	for _, l := range levels {
+		candidates := byLevel[l]
+		rng.Shuffle(len(candidates), func(i, j int) {
+			candidates[i], candidates[j] = candidates[j], candidates[i]
+		})
+
+		for _, skill := range candidates {
+			if !owned[skill.Name] && len(missingRequirements(skill, player, owned)) == 0 {
+				return skill, nil
+			}
+		}
+	}
+
+	return nil, ErrNoSkillsLeft
+}
+
+// ownedSkillNames returns the names of the catalogue skills the player has.
+func ownedSkillNames(store storage.Storage, playerId int, skills []*types.Skill) (map[string]bool, error) {
+	playerSkills, err := store.ListPlayerSkills(playerId)
 	if err != nil {
 		return nil, err
 	}
 
-	var skills []*types.Skill
+	names := map[int]string{}
+	for _, skill := range skills {
+		names[skill.ID] = skill.Name
+	}
+
+	owned := map[string]bool{}
+	for _, ps := range playerSkills {
+		owned[names[ps.SkillID]] = true
+	}
 
-	err = json.Unmarshal(data, &skills)
+	return owned, nil
+}
+
+func GivePlayerNewSkill(store storage.Storage, playerId string, skill *types.Skill) error {
+	id, err := strconv.Atoi(playerId)
 	if err != nil {
-		return nil, err
+		return err
 	}
 
-	rand.Shuffle(len(skills), func(i, j int) {
-		skills[i], skills[j] = skills[j], skills[i]
+	_, err = store.CreatePlayerSkill(&types.PlayerSkills{
+		SkillID:  skill.ID,
+		PlayerID: id,
 	})
 
-	for _, skill := range skills {
-		hasSkill, err := checkHavedSkill(skill)
+	return err
+}
+
+var (
+	// SkillCurve is how much proficiency XP each skill level takes.
+	SkillCurve = LevelCurve{Base: 100, Exponent: 1}
+	// SkillXP is multiplied by the quest priority when a duplicate drop
+	// upgrades a skill the player already owns.
+	SkillXP = 20
+)
+
+// SkillProgress is a skill together with the player's proficiency in it.
+type SkillProgress struct {
+	Skill *types.Skill `json:"skill"`
+	// Name includes the proficiency level, e.g. "Shadow Step Lv.3".
+	Name          string    `json:"name"`
+	Level         int       `json:"level"`
+	MaxLevel      int       `json:"max_level"`
+	XP            int       `json:"xp"`
+	XPToNextLevel int       `json:"xp_to_next_level"`
+	RecivedAt     time.Time `json:"recived_at"`
+	// CooldownLeft is in seconds and always 0 for passive skills.
+	CooldownLeft int `json:"cooldown_left"`
+}
+
+func newSkillProgress(skill *types.Skill, ps *types.PlayerSkills) *SkillProgress {
+	toNext := 0
+	if ps.Level < skill.MaxLevel {
+		toNext = SkillCurve.TotalXP(ps.Level+1) - ps.XP
+	}
+
+	return &SkillProgress{
+		Skill:         skill,
+		Name:          fmt.Sprintf("%s Lv.%d", skill.Name, ps.Level),
+		Level:         ps.Level,
+		MaxLevel:      skill.MaxLevel,
+		XP:            ps.XP,
+		XPToNextLevel: toNext,
+		RecivedAt:     ps.RecivedAt,
+	}
+}
+
+func GetSkillProgression(store storage.Storage, playerId string) ([]*SkillProgress, error) {
+	id, err := strconv.Atoi(playerId)
+	if err != nil {
+		return nil, err
+	}
+
+	owned, err := store.ListPlayerSkills(id)
+	if err != nil {
+		return nil, err
+	}
+
+	now := clock()
+	progression := []*SkillProgress{}
+	for _, ps := range owned {
+		skill, err := store.GetSkill(ps.SkillID)
 		if err != nil {
 			return nil, err
 		}
-		if *hasSkill {
-			return skill, nil
+
+		progress := newSkillProgress(skill, ps)
+		left, err := skillCooldownLeft(store, id, skill, now)
+		if err != nil {
+			return nil, err
 		}
+		progress.CooldownLeft = int(math.Ceil(left.Seconds()))
+
+		progression = append(progression, progress)
 	}
 
-	return RandomSkillLevelBased(playerId, level+1)
+	return progression, nil
 }
 
-func checkHavedSkill(skill *types.Skill) (*bool, error) {
-
-	idStr := strconv.Itoa(skill.ID)
-
-	data, _, err := db.SupabaseClient.From("player_skills").Select("*", "", false).Eq("skill", idStr).Execute()
+// UpgradeRandomSkill gives xp to a random skill the player owns that isn't
+// at its max level yet. It returns nil when every owned skill is maxed.
+func UpgradeRandomSkill(store storage.Storage, playerId int, xp int) (*SkillProgress, error) {
+	player, err := store.GetPlayer(playerId)
+	if err != nil {
+		return nil, err
+	}
 
+	owned, err := store.ListPlayerSkills(playerId)
 	if err != nil {
 		return nil, err
 	}
 
-	var playerSkills []*types.PlayerSkills
-	err = json.Unmarshal(data, &playerSkills)
+	rng, err := skillRand(store, player, "skill-upgrade")
 	if err != nil {
 		return nil, err
 	}
 
-	res := (len(playerSkills) == 0)
+	rng.Shuffle(len(owned), func(i, j int) {
+		owned[i], owned[j] = owned[j], owned[i]
+	})
 
-	return &res, nil
+	for _, ps := range owned {
+		skill, err := store.GetSkill(ps.SkillID)
+		if err != nil {
+			return nil, err
+		}
+		if ps.Level < skill.MaxLevel {
+			return addSkillXP(store, skill, ps, xp)
+		}
+	}
 
+	return nil, nil
 }
 
-func GivePlayerNewSkill(playerId string, skill *types.Skill) error {
-	_, _, err := db.SupabaseClient.From("player_skills").Insert(map[string]any{
-		"skill":  skill.ID,
-		"player": playerId,
-	}, false, "", "", "exact").Execute()
+func addSkillXP(store storage.Storage, skill *types.Skill, ps *types.PlayerSkills, xp int) (*SkillProgress, error) {
+	ps.XP += xp
+	ps.Level, _ = SkillCurve.Level(ps.XP)
 
-	return err
+	if ps.Level >= skill.MaxLevel {
+		ps.Level = skill.MaxLevel
+		ps.XP = SkillCurve.TotalXP(skill.MaxLevel)
+	}
+
+	if err := store.UpdatePlayerSkillProgress(ps.ID, ps.Level, ps.XP); err != nil {
+		return nil, err
+	}
+
+	return newSkillProgress(skill, ps), nil
 }
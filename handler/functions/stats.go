@@ -0,0 +1,84 @@
+package functions
+
+import (
+	"errors"
+	"strconv"
+
+	"github.com/MultiX0/solo_leveling_system/storage"
+	"github.com/MultiX0/solo_leveling_system/types"
+)
+
+var (
+	ErrInvalidAllocation   = errors.New("stat points must be positive numbers and at least one stat must be raised")
+	ErrNotEnoughStatPoints = errors.New("you don't have enough stat points")
+)
+
+// AllocateStats spends the player's free stat points on the given stats.
+func AllocateStats(store storage.Storage, playerId string, stats types.Stats) (*types.Player, error) {
+	id, err := strconv.Atoi(playerId)
+	if err != nil {
+		return nil, err
+	}
+
+	if stats.Strength < 0 || stats.Agility < 0 || stats.Sense < 0 ||
+		stats.Vitality < 0 || stats.Intelligence < 0 || stats.Total() == 0 {
+		return nil, ErrInvalidAllocation
+	}
+
+	player, err := store.GetPlayer(id)
+	if err != nil {
+		return nil, err
+	}
+
+	if stats.Total() > player.StatPoints {
+		return nil, ErrNotEnoughStatPoints
+	}
+
+	player, err = store.AddPlayerStats(id, stats, -stats.Total())
+	// another request spent the points first
+	if errors.Is(err, storage.ErrConstraint) {
+		return nil, ErrNotEnoughStatPoints
+	}
+
+	return player, err
+}
+
+// StatusWindow is the System's view of a player.
+type StatusWindow struct {
+	Name  string `json:"name"`
+	Title string `json:"title"`
+	PlayerProgress
+	Stats      types.Stats      `json:"stats"`
+	StatPoints int              `json:"stat_points"`
+	Mana       int              `json:"mana"`
+	MaxMana    int              `json:"max_mana"`
+	Skills     []*SkillProgress `json:"skills"`
+}
+
+func GetStatusWindow(store storage.Storage, playerId string) (*StatusWindow, error) {
+	player, err := GetPlayerByID(store, playerId)
+	if err != nil {
+		return nil, err
+	}
+
+	skills, err := GetSkillProgression(store, playerId)
+	if err != nil {
+		return nil, err
+	}
+
+	title := player.Title
+	if title == "" {
+		title = "None"
+	}
+
+	return &StatusWindow{
+		Name:           player.Name,
+		Title:          title,
+		PlayerProgress: GetPlayerProgress(player),
+		Stats:          player.Stats,
+		StatPoints:     player.StatPoints,
+		Mana:           CurrentMana(player, clock()),
+		MaxMana:        MaxMana(player),
+		Skills:         skills,
+	}, nil
+}
@@ -0,0 +1,156 @@
+package functions
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/MultiX0/solo_leveling_system/storage"
+	"github.com/MultiX0/solo_leveling_system/types"
+)
+
+// StreakMilestone is a bonus for completing the main quest Days days in a
+// row. Title is only given when it is set.
+type StreakMilestone struct {
+	Days  int    `json:"days"`
+	XP    int    `json:"xp"`
+	Title string `json:"title,omitempty"`
+}
+
+// StreakMilestones are sorted by Days.
+var StreakMilestones = []StreakMilestone{
+	{Days: 3, XP: 150},
+	{Days: 7, XP: 350, Title: "Persistent Hunter"},
+	{Days: 14, XP: 700},
+	{Days: 30, XP: 1500, Title: "The One Who Never Rests"},
+	{Days: 100, XP: 5000, Title: "Monarch of Discipline"},
+}
+
+// Streak is how many days in a row the player completed their main quest.
+type Streak struct {
+	Current int `json:"current"`
+	Longest int `json:"longest"`
+	// CompletedToday tells whether today already counts, a streak that
+	// isn't extended by the end of today is lost.
+	CompletedToday bool             `json:"completed_today"`
+	NextMilestone  *StreakMilestone `json:"next_milestone,omitempty"`
+}
+
+// currentStreak counts the days in a row, up to today, on which the player
+// completed a main quest, days starting at the player's daily reset.
+// Missing yesterday too breaks the streak.
+func currentStreak(store storage.Storage, playerId int) (int, bool, error) {
+	player, err := store.GetPlayer(playerId)
+	if err != nil {
+		return 0, false, err
+	}
+
+	completed, err := store.ListPlayerQuests(storage.PlayerQuestFilter{
+		PlayerID: playerId,
+		Status:   []int{types.QuestCompleted},
+		Kind:     storage.MainQuests,
+	})
+	if err != nil {
+		return 0, false, err
+	}
+
+	if len(completed) == 0 {
+		return 0, false, nil
+	}
+
+	today := playerDayStart(player, clock())
+	completedToday := playerDayStart(player, completed[0].StartAt).Equal(today)
+
+	// the day before is looked up rather than counted back 24 hours, days
+	// around a schedule change are longer
+	previousDay := func(day time.Time) time.Time {
+		return playerDayStart(player, day.Add(-time.Nanosecond))
+	}
+
+	streak := 0
+	next := today
+	if !completedToday {
+		next = previousDay(today)
+	}
+	for _, pq := range completed {
+		day := playerDayStart(player, pq.StartAt)
+		if day.After(next) {
+			continue
+		}
+		if !day.Equal(next) {
+			break
+		}
+		streak++
+		next = previousDay(next)
+	}
+
+	return streak, completedToday, nil
+}
+
+func nextStreakMilestone(streak int) *StreakMilestone {
+	for _, milestone := range StreakMilestones {
+		if milestone.Days > streak {
+			return &milestone
+		}
+	}
+	return nil
+}
+
+func streakMilestone(streak int) *StreakMilestone {
+	for _, milestone := range StreakMilestones {
+		if milestone.Days == streak {
+			return &milestone
+		}
+	}
+	return nil
+}
+
+// GetStreak returns the player's current and longest streaks.
+func GetStreak(store storage.Storage, playerId string) (*Streak, error) {
+	id, err := strconv.Atoi(playerId)
+	if err != nil {
+		return nil, err
+	}
+
+	player, err := store.GetPlayer(id)
+	if err != nil {
+		return nil, err
+	}
+
+	current, completedToday, err := currentStreak(store, id)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Streak{
+		Current:        current,
+		Longest:        max(player.LongestStreak, current),
+		CompletedToday: completedToday,
+		NextMilestone:  nextStreakMilestone(current),
+	}, nil
+}
+
+// extendStreak records the streak a just completed main quest made and
+// returns the milestone it reached, if any. The milestone's title is
+// given right away, its XP is left to the caller. It is meant to run
+// inside Atomic.
+func extendStreak(tx storage.Storage, playerId int) (int, *StreakMilestone, error) {
+	streak, _, err := currentStreak(tx, playerId)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	if err = tx.RecordPlayerStreak(playerId, streak); err != nil {
+		return 0, nil, err
+	}
+
+	milestone := streakMilestone(streak)
+	if milestone == nil || milestone.Title == "" {
+		return streak, milestone, nil
+	}
+
+	if err = tx.UpdatePlayerTitle(playerId, milestone.Title); err != nil {
+		return 0, nil, err
+	}
+
+	return streak, milestone, nil
+}
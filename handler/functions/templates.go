@@ -0,0 +1,57 @@
+package functions
+
+import (
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/MultiX0/solo_leveling_system/types"
+)
+
+// RenderQuest fills in a quest template for a player of the given level.
+// Every objective target grows by PerLevel per level, and {Name}
+// placeholders in the description are replaced by the objective's target,
+// e.g. "Complete {Push-ups} push-ups" becomes "Complete 30 push-ups".
+func RenderQuest(quest *types.Quest, level int) (string, []types.Objective) {
+	return renderQuest(quest, level, 1)
+}
+
+// renderQuest is RenderQuest with every target multiplied by factor.
+func renderQuest(quest *types.Quest, level int, factor float64) (string, []types.Objective) {
+	if len(quest.Objectives) == 0 {
+		return quest.Description, nil
+	}
+
+	objectives := make([]types.Objective, len(quest.Objectives))
+	placeholders := []string{}
+	for i, objective := range quest.Objectives {
+		target := objective.Target + objective.PerLevel*float64(level)
+		if objective.Max > 0 {
+			target = min(target, objective.Max)
+		}
+		target = math.Round(target*factor*100) / 100
+
+		objectives[i] = types.Objective{
+			Name:   objective.Name,
+			Target: target,
+			Unit:   objective.Unit,
+		}
+		placeholders = append(placeholders, "{"+objective.Name+"}", strconv.FormatFloat(target, 'f', -1, 64))
+	}
+
+	return strings.NewReplacer(placeholders...).Replace(quest.Description), objectives
+}
+
+// renderedQuest is the quest as it was given to the player. Quests given
+// before templates existed get the level 1 targets.
+func renderedQuest(quest *types.Quest, pq *types.PlayerQuest) *types.Quest {
+	rendered := *quest
+	rendered.Description, rendered.Objectives = RenderQuest(quest, 1)
+	if pq.Description != "" {
+		rendered.Description = pq.Description
+	}
+	if len(pq.Objectives) > 0 {
+		rendered.Objectives = pq.Objectives
+	}
+	return &rendered
+}
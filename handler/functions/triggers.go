@@ -0,0 +1,234 @@
+package functions
+
+import (
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/MultiX0/solo_leveling_system/storage"
+	"github.com/MultiX0/solo_leveling_system/types"
+)
+
+// MaxEmergencyQuestsPerDay caps how many emergency quests a player gets in
+// one of their days.
+var MaxEmergencyQuestsPerDay = 1
+
+// triggerState is what the conditions of a trigger are checked against.
+type triggerState struct {
+	player *types.Player
+	level  int
+	streak int
+	skills map[string]bool
+}
+
+// triggerConditions are the rules a trigger can set. A quest is only
+// triggered when every one of them holds.
+var triggerConditions = []func(trigger *types.QuestTrigger, state *triggerState) bool{
+	func(trigger *types.QuestTrigger, state *triggerState) bool {
+		return state.streak >= trigger.Streak
+	},
+	func(trigger *types.QuestTrigger, state *triggerState) bool {
+		return state.level >= trigger.Level
+	},
+	func(trigger *types.QuestTrigger, state *triggerState) bool {
+		return trigger.Skill == "" || state.skills[trigger.Skill]
+	},
+}
+
+// EventQuest is an emergency or hidden quest a trigger handed out.
+type EventQuest struct {
+	Quest    *ActiveQuest `json:"quest"`
+	Type     string       `json:"type"`
+	Deadline time.Time    `json:"deadline"`
+}
+
+// triggerMultiplier is how much more XP an event quest gives.
+func triggerMultiplier(quest *types.Quest) int {
+	if quest.Trigger == nil {
+		return 1
+	}
+	return max(quest.Trigger.Multiplier, 1)
+}
+
+func newTriggerState(store storage.Storage, playerId int) (*triggerState, error) {
+	player, err := store.GetPlayer(playerId)
+	if err != nil {
+		return nil, err
+	}
+
+	streak, _, err := currentStreak(store, playerId)
+	if err != nil {
+		return nil, err
+	}
+
+	skills, err := store.ListSkills()
+	if err != nil {
+		return nil, err
+	}
+
+	owned, err := ownedSkillNames(store, playerId, skills)
+	if err != nil {
+		return nil, err
+	}
+
+	level, _ := Curve.Level(player.XP)
+	return &triggerState{player: player, level: level, streak: streak, skills: owned}, nil
+}
+
+// canTrigger tells whether the player may get the quest again: hidden
+// quests are given once, emergency quests once a day at most and never
+// while another one is active.
+func canTrigger(store storage.Storage, state *triggerState, quest *types.Quest, given []*types.PlayerQuest) (bool, error) {
+	dayStart := playerDayStart(state.player, clock())
+	emergencies := 0
+	for _, pq := range given {
+		if pq.QuestID == quest.ID && (quest.Trigger.Type == types.TriggerHidden || pq.Status == types.QuestActive) {
+			return false, nil
+		}
+		if quest.Trigger.Type != types.TriggerEmergency {
+			continue
+		}
+
+		other, err := getQuestByID(store, strconv.Itoa(pq.QuestID))
+		if err != nil {
+			return false, err
+		}
+		if other.Trigger == nil || other.Trigger.Type != types.TriggerEmergency {
+			continue
+		}
+		if pq.Status == types.QuestActive {
+			return false, nil
+		}
+		if !pq.StartAt.Before(dayStart) {
+			emergencies++
+		}
+	}
+
+	return emergencies < MaxEmergencyQuestsPerDay, nil
+}
+
+// fireTriggers checks every event quest's trigger against the player and
+// hands out the quests whose conditions hold. It is meant to run inside
+// Atomic.
+func fireTriggers(tx storage.Storage, playerId int) ([]*types.Quest, error) {
+	// the Penalty Zone comes first
+	penalty, err := activePenalty(tx, playerId)
+	if err != nil || penalty != nil {
+		return nil, err
+	}
+
+	pool, err := questPool(tx, storage.EventQuests, playerId)
+	if err != nil || len(pool) == 0 {
+		return nil, err
+	}
+
+	state, err := newTriggerState(tx, playerId)
+	if err != nil {
+		return nil, err
+	}
+
+	effects, err := PassiveEffects(tx, playerId)
+	if err != nil {
+		return nil, err
+	}
+
+	var triggered []*types.Quest
+	for i := range pool {
+		quest := &pool[i]
+		if quest.Trigger == nil {
+			continue
+		}
+
+		met := true
+		for _, condition := range triggerConditions {
+			if !condition(quest.Trigger, state) {
+				met = false
+				break
+			}
+		}
+		if !met {
+			continue
+		}
+
+		// listed again for every quest so one given in this loop counts
+		given, err := tx.ListPlayerQuests(storage.PlayerQuestFilter{
+			PlayerID: playerId,
+			Kind:     storage.EventQuests,
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		ok, err := canTrigger(tx, state, quest, given)
+		if err != nil {
+			return nil, err
+		}
+		if !ok || (quest.Trigger.Chance > 0 && triggerRand(state.player, quest).Float64() >= quest.Trigger.Chance) {
+			continue
+		}
+
+		if _, err = insertQuestToPlayerQuests(tx, quest, playerId, effects); err != nil {
+			return nil, err
+		}
+		triggered = append(triggered, quest)
+	}
+
+	return triggered, nil
+}
+
+// FireTriggers checks the triggers of every player, each in their own
+// transaction so one failing player doesn't hold back the rest.
+func FireTriggers(store storage.Storage) error {
+	players, err := store.ListPlayers()
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	for _, player := range players {
+		errs = append(errs, store.Atomic(func(tx storage.Storage) error {
+			_, err := fireTriggers(tx, player.ID)
+			return err
+		}))
+	}
+
+	return errors.Join(errs...)
+}
+
+// GetEventQuests returns the player's active emergency and hidden quests.
+func GetEventQuests(store storage.Storage, playerId string) ([]*EventQuest, error) {
+	id, err := strconv.Atoi(playerId)
+	if err != nil {
+		return nil, err
+	}
+
+	active, err := store.ListPlayerQuests(storage.PlayerQuestFilter{
+		PlayerID: id,
+		Status:   []int{types.QuestActive},
+		Kind:     storage.EventQuests,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	events := []*EventQuest{}
+	for _, pq := range active {
+		quest, err := getQuestByID(store, strconv.Itoa(pq.QuestID))
+		if err != nil {
+			return nil, err
+		}
+
+		withProgress, err := WithProgress(store, playerId, quest)
+		if err != nil {
+			return nil, err
+		}
+
+		event := &EventQuest{Quest: withProgress[0], Deadline: pq.Deadline}
+		if quest.Trigger != nil {
+			event.Type = quest.Trigger.Type
+		}
+		events = append(events, event)
+	}
+
+	return events, nil
+}
@@ -0,0 +1,178 @@
+package functions
+
+import (
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/MultiX0/solo_leveling_system/storage"
+	"github.com/MultiX0/solo_leveling_system/storage/memory"
+	"github.com/MultiX0/solo_leveling_system/types"
+)
+
+// eventQuest adds an event quest with the trigger to the store.
+func eventQuest(t *testing.T, store *memory.Store, title string, priority int, trigger types.QuestTrigger) *types.Quest {
+	t.Helper()
+
+	quest, err := store.CreateQuest(&types.Quest{Title: title, Priority: priority, Cadence: types.CadenceEvent, Trigger: &trigger})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return quest
+}
+
+// fire runs the triggers like the hourly job does and returns the titles
+// of the player's active event quests.
+func fire(t *testing.T, store *memory.Store, player *types.Player) map[string]bool {
+	t.Helper()
+
+	if err := FireTriggers(store); err != nil {
+		t.Fatal(err)
+	}
+
+	active, err := store.ListPlayerQuests(storage.PlayerQuestFilter{
+		PlayerID: player.ID,
+		Kind:     storage.EventQuests,
+		Status:   []int{types.QuestActive},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	titles := map[string]bool{}
+	for _, pq := range active {
+		quest, err := store.GetQuest(pq.QuestID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		titles[quest.Title] = true
+	}
+
+	return titles
+}
+
+// complete finishes the player's active quests, so the triggers can give
+// them out again.
+func complete(t *testing.T, store *memory.Store, player *types.Player) {
+	t.Helper()
+
+	_, err := store.UpdatePlayerQuestStatus(storage.PlayerQuestFilter{
+		PlayerID: player.ID,
+		Status:   []int{types.QuestActive},
+	}, types.QuestCompleted)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestFireTriggers(t *testing.T) {
+	start := time.Date(2026, 10, 12, 10, 0, 0, 0, time.UTC)
+	store, player := newEmptyTestStore(t, start)
+
+	if _, err := store.CreateSkill(&types.Skill{Name: "Shadow Step", Level: 1, Type: types.SkillActive}); err != nil {
+		t.Fatal(err)
+	}
+
+	dungeonBreak := eventQuest(t, store, "Dungeon Break", 3, types.QuestTrigger{Type: types.TriggerEmergency, Level: 2, Hours: 3, Multiplier: 3})
+	eventQuest(t, store, "Rescue the Hunters", 2, types.QuestTrigger{Type: types.TriggerEmergency, Hours: 2})
+	eventQuest(t, store, "Shadows in the Dark", 5, types.QuestTrigger{Type: types.TriggerHidden, Skill: "Shadow Step", Hours: 24})
+
+	steps := []struct {
+		name   string
+		before func()
+		want   []string
+	}{
+		{
+			name: "a level 1 player without skills",
+			want: []string{"Rescue the Hunters"},
+		},
+		{
+			name: "while the emergency is active",
+			want: []string{"Rescue the Hunters"},
+		},
+		{
+			name:   "after the day's emergency was completed",
+			before: func() { complete(t, store, player) },
+		},
+		{
+			name: "at level 2 on the same day",
+			before: func() {
+				if _, err := store.AddPlayerXP(player.ID, Curve.TotalXP(2)); err != nil {
+					t.Fatal(err)
+				}
+			},
+		},
+		{
+			name:   "on the next day",
+			before: func() { clock = func() time.Time { return start.Add(24 * time.Hour) } },
+			want:   []string{"Dungeon Break"},
+		},
+		{
+			name: "after getting the skill",
+			before: func() {
+				skill, err := store.GetSkillByName("Shadow Step")
+				if err != nil {
+					t.Fatal(err)
+				}
+				if _, err = store.CreatePlayerSkill(&types.PlayerSkills{PlayerID: player.ID, SkillID: skill.ID}); err != nil {
+					t.Fatal(err)
+				}
+			},
+			want: []string{"Dungeon Break", "Shadows in the Dark"},
+		},
+		{
+			name: "days after the hidden quest was completed",
+			before: func() {
+				complete(t, store, player)
+				clock = func() time.Time { return start.Add(72 * time.Hour) }
+			},
+			want: []string{"Dungeon Break"},
+		},
+	}
+
+	for _, step := range steps {
+		if step.before != nil {
+			step.before()
+		}
+
+		active := fire(t, store, player)
+		if len(active) != len(step.want) {
+			t.Errorf("%s: active %v, want %v", step.name, active, step.want)
+			continue
+		}
+		for _, title := range step.want {
+			if !active[title] {
+				t.Errorf("%s: active %v, want %v", step.name, active, step.want)
+			}
+		}
+	}
+
+	reward, err := FinishQuest(store, strconv.Itoa(player.ID), strconv.Itoa(dungeonBreak.ID))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := 3 * QuestXP * priorityWeight(dungeonBreak.Priority); reward.XP != want {
+		t.Errorf("finishing the emergency quest gave %d xp, want %d", reward.XP, want)
+	}
+}
+
+func TestEventQuestXP(t *testing.T) {
+	trigger := &types.QuestTrigger{Type: types.TriggerEmergency, Hours: 3, Multiplier: 3}
+
+	tests := []struct {
+		name  string
+		quest *types.Quest
+		want  int
+	}{
+		{"emergency quest", &types.Quest{Priority: 3, Cadence: types.CadenceEvent, Trigger: trigger}, 3 * QuestXP * priorityWeight(3)},
+		{"no multiplier", &types.Quest{Priority: 3, Cadence: types.CadenceEvent, Trigger: &types.QuestTrigger{Type: types.TriggerHidden}}, QuestXP * priorityWeight(3)},
+		{"daily quest", &types.Quest{Priority: 3, Cadence: types.CadenceDaily}, QuestXP * priorityWeight(3)},
+	}
+
+	for _, test := range tests {
+		if got := QuestXPReward(test.quest); got != test.want {
+			t.Errorf("%s: %d xp, want %d", test.name, got, test.want)
+		}
+	}
+}
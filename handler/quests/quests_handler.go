@@ -1,12 +1,17 @@
 package quests
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
+	"strings"
 	"sync"
 
 	"github.com/MultiX0/solo_leveling_system/handler/functions"
+	"github.com/MultiX0/solo_leveling_system/storage"
+	"github.com/MultiX0/solo_leveling_system/types"
 	"github.com/MultiX0/solo_leveling_system/utils"
 	"github.com/gorilla/mux"
 )
@@ -17,12 +22,13 @@ var (
 )
 
 type QuestsHandler struct {
-	mu sync.RWMutex
+	mu    sync.RWMutex
+	store storage.Storage
 }
 
-func GetNewQuestsHandler() *QuestsHandler {
+func GetNewQuestsHandler(store storage.Storage) *QuestsHandler {
 	handlerOnce.Do(func() {
-		handlerInstance = &QuestsHandler{}
+		handlerInstance = &QuestsHandler{store: store}
 	})
 
 	return handlerInstance
@@ -31,6 +37,15 @@ func GetNewQuestsHandler() *QuestsHandler {
 func (h *QuestsHandler) RoutesHandler(router *mux.Router) {
 	router.HandleFunc("/player/{id}/quests", h.FetchQuests).Methods("GET")
 	router.HandleFunc("/player/{id}/finish/{questId}", h.FinishQuest).Methods("GET")
+	router.HandleFunc("/player/{id}/quests/history", h.GetQuestHistory).Methods("GET")
+	router.HandleFunc("/player/{id}/quests/{questId}/progress", h.ReportProgress).Methods("POST")
+	router.HandleFunc("/player/{id}/quests/{questId}/abandon", h.AbandonQuest).Methods("POST")
+	router.HandleFunc("/player/{id}/quests/{questId}/reroll", h.RerollQuest).Methods("POST")
+	router.HandleFunc("/player/{id}/chains", h.GetChains).Methods("GET")
+	router.HandleFunc("/player/{id}/custom-quests", h.ListCustomQuests).Methods("GET")
+	router.HandleFunc("/player/{id}/custom-quests", h.CreateCustomQuest).Methods("POST")
+	router.HandleFunc("/player/{id}/custom-quests/{questId}", h.UpdateCustomQuest).Methods("PUT")
+	router.HandleFunc("/player/{id}/custom-quests/{questId}", h.DeleteCustomQuest).Methods("DELETE")
 
 }
 
@@ -45,18 +60,330 @@ func (h *QuestsHandler) FinishQuest(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	skill, err := functions.FinishQuest(playerId, questId)
+	reward, err := functions.FinishQuest(h.store, playerId, questId)
 	if err != nil {
 		log.Println(err)
-		utils.WriteError(w, http.StatusBadGateway, err)
+		utils.WriteError(w, finishQuestStatus(err), err)
+		return
+	}
+
+	utils.WriteJsonResponse(w, http.StatusAccepted, map[string]any{
+		"message": rewardMessage(reward),
+		"skill":   reward.Skill,
+		"reward":  reward,
+	})
+
+}
+
+func rewardMessage(reward *functions.QuestReward) string {
+	message := "congrats you got a new skill!"
+	if reward.PenaltyCleared {
+		message = "you survived the Penalty Zone, your main quests are back"
+	} else if reward.UpgradedSkill != nil {
+		message = fmt.Sprintf("your skill grew stronger: %s", reward.UpgradedSkill.Name)
+	} else if reward.Skill == nil {
+		message = "quest completed, all your skills are already at their max level"
+	}
+	if chain := reward.Chain; chain != nil {
+		if chain.Completed {
+			message = fmt.Sprintf("%s You finished the %q chain!", message, chain.Chain)
+		} else {
+			message = fmt.Sprintf("%s %s %d/%d done, %q unlocked.", message, chain.Chain, chain.Step, chain.Steps, chain.Next.Title)
+		}
+	}
+	for _, quest := range reward.Triggered {
+		kind := "Hidden Quest"
+		if quest.Trigger != nil && quest.Trigger.Type == types.TriggerEmergency {
+			kind = "Emergency Quest"
+		}
+		message = fmt.Sprintf("%s %s unlocked: %q.", message, kind, quest.Title)
+	}
+	if milestone := reward.StreakMilestone; milestone != nil {
+		streak := fmt.Sprintf("%d day streak! +%d xp.", milestone.Days, milestone.XP)
+		if milestone.Title != "" {
+			streak = fmt.Sprintf("%s You earned the title %q.", streak, milestone.Title)
+		}
+		message = streak + " " + message
+	}
+	if reward.LeveledUp {
+		message = fmt.Sprintf("Level up! you are now level %d. %s", reward.Progress.Level, message)
+	}
+	return message
+}
+
+func finishQuestStatus(err error) int {
+	switch {
+	case errors.Is(err, functions.ErrQuestNotAssigned):
+		return http.StatusNotFound
+	case errors.Is(err, functions.ErrQuestNotActive):
+		return http.StatusConflict
+	case errors.Is(err, functions.ErrNoObjectives), errors.Is(err, functions.ErrInvalidProgress):
+		return http.StatusBadRequest
+	}
+	return http.StatusBadGateway
+}
+
+func (h *QuestsHandler) ReportProgress(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	playerId := params["id"]
+	questId := params["questId"]
+
+	if len(playerId) == 0 || len(questId) == 0 {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("invalid player ID and quest ID"))
+		return
+	}
+
+	type RequestBody struct {
+		Progress map[string]float64 `json:"progress"`
+	}
+
+	var body RequestBody
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		log.Println(err)
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf(`please provide the progress per objective such as {"progress": {"Push-ups": 20}}`))
+		return
+	}
+
+	progress, err := functions.ReportProgress(h.store, playerId, questId, body.Progress)
+	if err != nil {
+		log.Println(err)
+		utils.WriteError(w, finishQuestStatus(err), err)
+		return
+	}
+
+	if !progress.Completed {
+		utils.WriteJsonResponse(w, http.StatusOK, map[string]any{
+			"message":  "progress saved, keep going!",
+			"progress": progress,
+		})
 		return
 	}
 
 	utils.WriteJsonResponse(w, http.StatusAccepted, map[string]any{
-		"message": "congrats you got a new skill!",
-		"skill":   skill,
+		"message":  "all objectives reached, quest completed! " + rewardMessage(progress.Reward),
+		"progress": progress,
+	})
+}
+
+func (h *QuestsHandler) AbandonQuest(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	playerId := params["id"]
+	questId := params["questId"]
+
+	if len(playerId) == 0 || len(questId) == 0 {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("invalid player ID and quest ID"))
+		return
+	}
+
+	abandoned, err := functions.AbandonQuest(h.store, playerId, questId)
+	if err != nil {
+		log.Println(err)
+		utils.WriteError(w, rerollStatus(err), err)
+		return
+	}
+
+	utils.WriteJsonResponse(w, http.StatusOK, map[string]any{
+		"message":   fmt.Sprintf("quest abandoned, you lost %d xp points", abandoned.Punishment),
+		"abandoned": abandoned,
+	})
+}
+
+func (h *QuestsHandler) RerollQuest(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	playerId := params["id"]
+	questId := params["questId"]
+
+	if len(playerId) == 0 || len(questId) == 0 {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("invalid player ID and quest ID"))
+		return
+	}
+
+	reroll, err := functions.RerollQuest(h.store, playerId, questId)
+	if err != nil {
+		log.Println(err)
+		utils.WriteError(w, rerollStatus(err), err)
+		return
+	}
+
+	utils.WriteJsonResponse(w, http.StatusOK, map[string]any{
+		"message": fmt.Sprintf("the System gave you a new quest for %d xp points, %d rerolls left today", reroll.Cost, reroll.RerollsLeft),
+		"reroll":  reroll,
+	})
+}
+
+func rerollStatus(err error) int {
+	switch {
+	case errors.Is(err, functions.ErrCannotAbandon), errors.Is(err, functions.ErrCannotReroll):
+		return http.StatusBadRequest
+	case errors.Is(err, functions.ErrNoRerollsLeft), errors.Is(err, functions.ErrNotEnoughXP),
+		errors.Is(err, functions.ErrNothingToReroll):
+		return http.StatusConflict
+	}
+	return finishQuestStatus(err)
+}
+
+// queryList reads a query parameter that may be repeated or hold a comma
+// separated list, e.g. ?status=completed,expired.
+func queryList(r *http.Request, key string) []string {
+	var list []string
+	for _, value := range r.URL.Query()[key] {
+		for _, item := range strings.Split(value, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				list = append(list, item)
+			}
+		}
+	}
+	return list
+}
+
+func (h *QuestsHandler) GetQuestHistory(w http.ResponseWriter, r *http.Request) {
+	playerId := mux.Vars(r)["id"]
+	if len(playerId) == 0 {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("invalid player ID"))
+		return
+	}
+
+	query := r.URL.Query()
+	history, err := functions.GetQuestHistory(h.store, playerId, functions.HistoryFilter{
+		Status:   queryList(r, "status"),
+		Priority: queryList(r, "priority"),
+		From:     query.Get("from"),
+		To:       query.Get("to"),
+		Limit:    query.Get("limit"),
+		Cursor:   query.Get("cursor"),
 	})
+	if err != nil {
+		log.Println(err)
+		status := http.StatusBadGateway
+		switch {
+		case errors.Is(err, functions.ErrInvalidHistoryFilter):
+			status = http.StatusBadRequest
+		case errors.Is(err, storage.ErrNotFound):
+			status = http.StatusNotFound
+		}
+		utils.WriteError(w, status, err)
+		return
+	}
+
+	utils.WriteJsonResponse(w, http.StatusOK, history)
+}
+
+func (h *QuestsHandler) GetChains(w http.ResponseWriter, r *http.Request) {
+	playerId := mux.Vars(r)["id"]
+	if len(playerId) == 0 {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("invalid player ID"))
+		return
+	}
+
+	chains, err := functions.GetChains(h.store, playerId)
+	if err != nil {
+		log.Println(err)
+		status := http.StatusBadGateway
+		if errors.Is(err, storage.ErrNotFound) {
+			status = http.StatusNotFound
+		}
+		utils.WriteError(w, status, err)
+		return
+	}
+
+	utils.WriteJsonResponse(w, http.StatusOK, chains)
+}
+
+func (h *QuestsHandler) ListCustomQuests(w http.ResponseWriter, r *http.Request) {
+	playerId := mux.Vars(r)["id"]
+	if len(playerId) == 0 {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("invalid player ID"))
+		return
+	}
+
+	quests, err := functions.ListCustomQuests(h.store, playerId)
+	if err != nil {
+		log.Println(err)
+		utils.WriteError(w, customQuestStatus(err), err)
+		return
+	}
 
+	utils.WriteJsonResponse(w, http.StatusOK, quests)
+}
+
+func (h *QuestsHandler) CreateCustomQuest(w http.ResponseWriter, r *http.Request) {
+	playerId := mux.Vars(r)["id"]
+	if len(playerId) == 0 {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("invalid player ID"))
+		return
+	}
+
+	var quest types.Quest
+	if err := json.NewDecoder(r.Body).Decode(&quest); err != nil {
+		log.Println(err)
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("please provide the quest such as (title, description, priority, objectives, cadence)"))
+		return
+	}
+
+	created, err := functions.CreateCustomQuest(h.store, playerId, &quest)
+	if err != nil {
+		log.Println(err)
+		utils.WriteError(w, customQuestStatus(err), err)
+		return
+	}
+
+	utils.WriteJsonResponse(w, http.StatusCreated, created)
+}
+
+func (h *QuestsHandler) UpdateCustomQuest(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	playerId := params["id"]
+	questId := params["questId"]
+	if len(playerId) == 0 || len(questId) == 0 {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("invalid player ID and quest ID"))
+		return
+	}
+
+	var quest types.Quest
+	if err := json.NewDecoder(r.Body).Decode(&quest); err != nil {
+		log.Println(err)
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("please provide the quest such as (title, description, priority, objectives, cadence)"))
+		return
+	}
+
+	updated, err := functions.UpdateCustomQuest(h.store, playerId, questId, &quest)
+	if err != nil {
+		log.Println(err)
+		utils.WriteError(w, customQuestStatus(err), err)
+		return
+	}
+
+	utils.WriteJsonResponse(w, http.StatusOK, updated)
+}
+
+func (h *QuestsHandler) DeleteCustomQuest(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	playerId := params["id"]
+	questId := params["questId"]
+	if len(playerId) == 0 || len(questId) == 0 {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("invalid player ID and quest ID"))
+		return
+	}
+
+	if err := functions.DeleteCustomQuest(h.store, playerId, questId); err != nil {
+		log.Println(err)
+		utils.WriteError(w, customQuestStatus(err), err)
+		return
+	}
+
+	utils.WriteJsonResponse(w, http.StatusOK, map[string]string{"message": "quest deleted"})
+}
+
+func customQuestStatus(err error) int {
+	switch {
+	case errors.Is(err, functions.ErrInvalidCustomQuest):
+		return http.StatusBadRequest
+	case errors.Is(err, functions.ErrCustomQuestNotFound), errors.Is(err, storage.ErrNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, functions.ErrTooManyCustomQuests), errors.Is(err, functions.ErrQuestTitleTaken):
+		return http.StatusConflict
+	}
+	return http.StatusBadGateway
 }
 
 func (h *QuestsHandler) FetchQuests(w http.ResponseWriter, r *http.Request) {
@@ -72,14 +399,32 @@ func (h *QuestsHandler) FetchQuests(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	mainQuest, err, mainStartTime := functions.GetMainQuest(playerId)
+	mainQuest, err, mainDeadline := functions.GetMainQuest(h.store, playerId)
+	if err != nil {
+		log.Println(err)
+		utils.WriteError(w, http.StatusBadGateway, err)
+		return
+	}
+
+	sideQuests, err, sideDeadline := functions.GetSideQuests(h.store, playerId)
 	if err != nil {
 		log.Println(err)
 		utils.WriteError(w, http.StatusBadGateway, err)
 		return
 	}
 
-	sideQuests, err, sideStartTime := functions.GetSideQuests(playerId)
+	var main any
+	if mainQuest != nil {
+		withProgress, err := functions.WithProgress(h.store, playerId, mainQuest)
+		if err != nil {
+			log.Println(err)
+			utils.WriteError(w, http.StatusBadGateway, err)
+			return
+		}
+		main = withProgress[0]
+	}
+
+	sides, err := functions.WithProgress(h.store, playerId, sideQuests...)
 	if err != nil {
 		log.Println(err)
 		utils.WriteError(w, http.StatusBadGateway, err)
@@ -88,29 +433,92 @@ func (h *QuestsHandler) FetchQuests(w http.ResponseWriter, r *http.Request) {
 
 	var timeLeft string
 	if mainQuest != nil {
-		timeLeft = utils.TimeLeft(mainStartTime)
+		timeLeft = utils.TimeUntil(mainDeadline)
 	} else if len(sideQuests) > 0 {
-		timeLeft = utils.TimeLeft(sideStartTime)
+		timeLeft = utils.TimeUntil(sideDeadline)
+	}
+
+	punishment, err := functions.PlayerPunishment(h.store, playerId)
+	if err != nil {
+		log.Println(err)
+		utils.WriteError(w, http.StatusBadGateway, err)
+		return
+	}
+
+	penaltyZone, err := functions.GetPenaltyZone(h.store, playerId)
+	if err != nil {
+		log.Println(err)
+		utils.WriteError(w, http.StatusBadGateway, err)
+		return
+	}
+
+	var penalty any
+	if penaltyZone != nil {
+		penalty = map[string]any{
+			"message":    "You failed your daily quest and were sent to the Penalty Zone, no main quests until you survive it",
+			"quest":      penaltyZone.Quest,
+			"escalation": penaltyZone.Escalation,
+			"time_left":  utils.TimeUntil(penaltyZone.Deadline),
+			"punishment": fmt.Sprintf("You will lose %d xp points.", punishment*penaltyZone.Escalation),
+		}
+	}
+
+	streak, err := functions.GetStreak(h.store, playerId)
+	if err != nil {
+		log.Println(err)
+		utils.WriteError(w, http.StatusBadGateway, err)
+		return
+	}
+
+	weekly, err := h.periodQuests(playerId, types.CadenceWeekly)
+	if err != nil {
+		log.Println(err)
+		utils.WriteError(w, http.StatusBadGateway, err)
+		return
+	}
+
+	monthly, err := h.periodQuests(playerId, types.CadenceMonthly)
+	if err != nil {
+		log.Println(err)
+		utils.WriteError(w, http.StatusBadGateway, err)
+		return
+	}
+
+	events, err := h.eventQuests(playerId)
+	if err != nil {
+		log.Println(err)
+		utils.WriteError(w, http.StatusBadGateway, err)
+		return
 	}
 
 	type Response struct {
-		MainQuest  any    `json:"main_quest"`
-		SideQuests any    `json:"side_quests"`
-		TimeLeft   string `json:"time_left"`
-		Punishment string `json:"punishment"`
+		Streak      *functions.Streak `json:"streak"`
+		PenaltyZone any               `json:"penalty_zone,omitempty"`
+		EventQuests any               `json:"event_quests,omitempty"`
+		MainQuest   any               `json:"main_quest"`
+		SideQuests  any               `json:"side_quests"`
+		TimeLeft    string            `json:"time_left"`
+		Punishment  string            `json:"punishment"`
+		Weekly      any               `json:"weekly_quests"`
+		Monthly     any               `json:"monthly_quests"`
 	}
 
 	var response any
 
 	response = Response{
-		MainQuest:  mainQuest,
-		SideQuests: sideQuests,
-		TimeLeft:   timeLeft,
-		Punishment: "You will lose 250 xp points.",
+		Streak:      streak,
+		PenaltyZone: penalty,
+		EventQuests: events,
+		MainQuest:   main,
+		SideQuests:  sides,
+		TimeLeft:    timeLeft,
+		Punishment:  fmt.Sprintf("You will lose %d xp points.", punishment),
+		Weekly:      weekly,
+		Monthly:     monthly,
 	}
 
-	if (mainQuest == nil) && (len(sideQuests) == 0) {
-		mainT, err := functions.TimeForQuest(true, playerId)
+	if (mainQuest == nil) && (len(sideQuests) == 0) && (penaltyZone == nil) {
+		mainT, err := functions.TimeForQuest(h.store, true, playerId)
 
 		if err != nil {
 			log.Println(err)
@@ -118,7 +526,7 @@ func (h *QuestsHandler) FetchQuests(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 
-		sideT, err := functions.TimeForQuest(false, playerId)
+		sideT, err := functions.TimeForQuest(h.store, false, playerId)
 
 		if err != nil {
 			log.Println(err)
@@ -126,15 +534,86 @@ func (h *QuestsHandler) FetchQuests(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 
-		nextMainQuest := utils.TimeLeft(*mainT)
-		nextSideQuests := utils.TimeLeft(*sideT)
+		mainReset, err := functions.PlayerNextReset(h.store, playerId, *mainT)
+		if err != nil {
+			log.Println(err)
+			utils.WriteError(w, http.StatusBadGateway, err)
+			return
+		}
+
+		sideReset, err := functions.PlayerNextReset(h.store, playerId, *sideT)
+		if err != nil {
+			log.Println(err)
+			utils.WriteError(w, http.StatusBadGateway, err)
+			return
+		}
+
+		nextMainQuest := utils.TimeUntil(mainReset)
+		nextSideQuests := utils.TimeUntil(sideReset)
 
-		response = map[string]string{
+		done := map[string]any{
 			"message":          "You have finished all your tasks for today, new tasks will be released tomorrow, be prepared",
+			"streak":           streak,
+			"weekly_quests":    weekly,
+			"monthly_quests":   monthly,
 			"next_main_quest":  nextMainQuest,
 			"next_side_quests": nextSideQuests,
 		}
+		if events != nil {
+			done["event_quests"] = events
+		}
+		response = done
 	}
 
 	utils.WriteJsonResponse(w, http.StatusOK, response)
 }
+
+// periodQuests is the weekly or monthly section of FetchQuests.
+func (h *QuestsHandler) periodQuests(playerId string, cadence string) (map[string]any, error) {
+	quests, err, deadline := functions.GetPeriodQuests(h.store, playerId, cadence)
+	if err != nil {
+		return nil, err
+	}
+
+	withProgress, err := functions.WithProgress(h.store, playerId, quests...)
+	if err != nil {
+		return nil, err
+	}
+
+	section := map[string]any{"quests": withProgress}
+	if len(quests) > 0 {
+		section["time_left"] = utils.TimeUntil(deadline)
+	}
+
+	return section, nil
+}
+
+// eventQuests is the emergency and hidden quest section of FetchQuests.
+// It is a nil interface when the player has none, so omitempty leaves it
+// out.
+func (h *QuestsHandler) eventQuests(playerId string) (any, error) {
+	events, err := functions.GetEventQuests(h.store, playerId)
+	if err != nil {
+		return nil, err
+	}
+
+	var section []map[string]any
+	for _, event := range events {
+		message := "You found a Hidden Quest!"
+		if event.Type == types.TriggerEmergency {
+			message = "Emergency Quest! Finish it before the time runs out"
+		}
+		section = append(section, map[string]any{
+			"message":   message,
+			"type":      event.Type,
+			"quest":     event.Quest,
+			"time_left": utils.TimeUntil(event.Deadline),
+		})
+	}
+
+	if len(section) == 0 {
+		return nil, nil
+	}
+
+	return section, nil
+}
@@ -0,0 +1,271 @@
+package quests
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/MultiX0/solo_leveling_system/handler/functions"
+	"github.com/MultiX0/solo_leveling_system/storage"
+	"github.com/MultiX0/solo_leveling_system/storage/memory"
+	"github.com/MultiX0/solo_leveling_system/types"
+	"github.com/gorilla/mux"
+)
+
+type testServer struct {
+	t      *testing.T
+	store  storage.Storage
+	router *mux.Router
+	player int
+}
+
+// newTestServer serves the quest routes from a memory store seeded with
+// the catalogue, with one new player in it.
+func newTestServer(t *testing.T) *testServer {
+	defer func(rolls functions.RollSource) {
+		t.Cleanup(func() { functions.Rolls = rolls })
+	}(functions.Rolls)
+	functions.Rolls = functions.SeededRolls{Seed: 1}
+
+	quests, err := storage.ReadQuests("../../quests.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	skills, err := storage.ReadSkills("../../skills.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	store := memory.New()
+	if err = storage.Seed(store, quests, skills); err != nil {
+		t.Fatal(err)
+	}
+
+	player, err := store.CreatePlayer(&types.Player{Name: "Jinwoo", Gender: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	router := mux.NewRouter()
+	(&QuestsHandler{store: store}).RoutesHandler(router)
+
+	return &testServer{t: t, store: store, router: router, player: player.ID}
+}
+
+// do sends the request for the path under the player and decodes the
+// response into out, when given.
+func (s *testServer) do(method, path string, out any) int {
+	s.t.Helper()
+
+	req := httptest.NewRequest(method, fmt.Sprintf("/player/%d%s", s.player, path), nil)
+	rec := httptest.NewRecorder()
+	s.router.ServeHTTP(rec, req)
+
+	if out != nil && rec.Code < 300 {
+		if err := json.Unmarshal(rec.Body.Bytes(), out); err != nil {
+			s.t.Fatalf("%s %s: %v", method, path, err)
+		}
+	}
+
+	return rec.Code
+}
+
+type questsResponse struct {
+	MainQuest  *types.Quest   `json:"main_quest"`
+	SideQuests []*types.Quest `json:"side_quests"`
+}
+
+func (s *testServer) quests() questsResponse {
+	s.t.Helper()
+
+	var quests questsResponse
+	if code := s.do("GET", "/quests", &quests); code != http.StatusOK {
+		s.t.Fatalf("fetching quests: status %d", code)
+	}
+	if quests.MainQuest == nil || len(quests.SideQuests) == 0 {
+		s.t.Fatalf("no main or side quests handed out: %+v", quests)
+	}
+
+	return quests
+}
+
+func (s *testServer) history(query string) functions.QuestHistory {
+	s.t.Helper()
+
+	var history functions.QuestHistory
+	if code := s.do("GET", "/quests/history"+query, &history); code != http.StatusOK {
+		s.t.Fatalf("fetching history%s: status %d", query, code)
+	}
+
+	return history
+}
+
+func TestFinishQuest(t *testing.T) {
+	s := newTestServer(t)
+	main := s.quests().MainQuest
+
+	var finished struct {
+		Reward functions.QuestReward `json:"reward"`
+	}
+	if code := s.do("GET", fmt.Sprintf("/finish/%d", main.ID), &finished); code != http.StatusAccepted {
+		t.Fatalf("finishing the main quest: status %d, want %d", code, http.StatusAccepted)
+	}
+
+	player, err := s.store.GetPlayer(s.player)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if player.XP == 0 {
+		t.Error("finishing a quest gave no xp")
+	}
+
+	if code := s.do("GET", fmt.Sprintf("/finish/%d", main.ID), nil); code != http.StatusConflict {
+		t.Errorf("finishing it again: status %d, want %d", code, http.StatusConflict)
+	}
+	if code := s.do("GET", "/finish/999999", nil); code != http.StatusNotFound {
+		t.Errorf("finishing a quest never given: status %d, want %d", code, http.StatusNotFound)
+	}
+
+	// the finished main quest isn't handed out again today
+	var quests questsResponse
+	if code := s.do("GET", "/quests", &quests); code != http.StatusOK {
+		t.Fatalf("fetching quests: status %d", code)
+	}
+	if quests.MainQuest != nil {
+		t.Errorf("got main quest %q after finishing today's", quests.MainQuest.Title)
+	}
+}
+
+func TestAbandonAndRerollQuest(t *testing.T) {
+	s := newTestServer(t)
+	quests := s.quests()
+
+	if code := s.do("POST", fmt.Sprintf("/quests/%d/abandon", quests.MainQuest.ID), nil); code != http.StatusBadRequest {
+		t.Errorf("abandoning the daily main quest: status %d, want %d", code, http.StatusBadRequest)
+	}
+	if code := s.do("POST", fmt.Sprintf("/quests/%d/reroll", quests.MainQuest.ID), nil); code != http.StatusBadRequest {
+		t.Errorf("rerolling the daily main quest: status %d, want %d", code, http.StatusBadRequest)
+	}
+
+	side := quests.SideQuests[0]
+	if code := s.do("POST", fmt.Sprintf("/quests/%d/reroll", side.ID), nil); code != http.StatusConflict {
+		t.Errorf("rerolling without the xp: status %d, want %d", code, http.StatusConflict)
+	}
+
+	if _, err := s.store.AddPlayerXP(s.player, 10*functions.RerollCostXP); err != nil {
+		t.Fatal(err)
+	}
+
+	var rerolled struct {
+		Reroll functions.Reroll `json:"reroll"`
+	}
+	if code := s.do("POST", fmt.Sprintf("/quests/%d/reroll", side.ID), &rerolled); code != http.StatusOK {
+		t.Fatalf("rerolling a side quest: status %d, want %d", code, http.StatusOK)
+	}
+	if rerolled.Reroll.Quest == nil || rerolled.Reroll.Quest.ID == side.ID {
+		t.Fatalf("rerolled into %+v", rerolled.Reroll.Quest)
+	}
+	if rerolled.Reroll.RerollsLeft != functions.MaxRerollsPerDay-1 {
+		t.Errorf("%d rerolls left, want %d", rerolled.Reroll.RerollsLeft, functions.MaxRerollsPerDay-1)
+	}
+
+	var abandoned struct {
+		Abandoned functions.AbandonedQuest `json:"abandoned"`
+	}
+	if code := s.do("POST", fmt.Sprintf("/quests/%d/abandon", rerolled.Reroll.Quest.ID), &abandoned); code != http.StatusOK {
+		t.Fatalf("abandoning a side quest: status %d, want %d", code, http.StatusOK)
+	}
+	if abandoned.Abandoned.Punishment == 0 {
+		t.Error("abandoning a side quest cost nothing")
+	}
+	if code := s.do("POST", fmt.Sprintf("/quests/%d/abandon", rerolled.Reroll.Quest.ID), nil); code != http.StatusConflict {
+		t.Errorf("abandoning it again: status %d, want %d", code, http.StatusConflict)
+	}
+
+	history := s.history("?status=abandoned")
+	if history.Counts["abandoned"] != 2 || history.Counts["active"] != 0 {
+		t.Errorf("counts %v, want the rerolled and the abandoned quest", history.Counts)
+	}
+	if len(history.Quests) != 2 || history.Quests[0].RerolledFrom == 0 {
+		t.Errorf("history %+v, want the abandoned reroll first", history.Quests)
+	}
+}
+
+func TestQuestHistory(t *testing.T) {
+	s := newTestServer(t)
+	quests := s.quests()
+
+	if code := s.do("GET", fmt.Sprintf("/finish/%d", quests.MainQuest.ID), nil); code != http.StatusAccepted {
+		t.Fatalf("finishing the main quest: status %d", code)
+	}
+
+	// weekly and monthly quests are handed out with the daily ones
+	all := s.history("")
+	if all.Total < 1+len(quests.SideQuests) || all.Counts["completed"] != 1 || all.Counts["active"] != all.Total-1 {
+		t.Fatalf("total %d and counts %v after finishing one quest", all.Total, all.Counts)
+	}
+	if len(all.Quests) != all.Total {
+		t.Fatalf("%d quests listed, want all %d", len(all.Quests), all.Total)
+	}
+
+	// a page at a time gives the same quests in the same order
+	var paged []*functions.HistoryEntry
+	cursor := ""
+	for {
+		query := "?limit=1"
+		if cursor != "" {
+			query += "&cursor=" + cursor
+		}
+		page := s.history(query)
+		if page.Total != all.Total {
+			t.Errorf("page total %d, want %d", page.Total, all.Total)
+		}
+		paged = append(paged, page.Quests...)
+		if cursor = page.NextCursor; cursor == "" {
+			break
+		}
+		if len(paged) > all.Total {
+			t.Fatal("the pages never end")
+		}
+	}
+	if len(paged) != len(all.Quests) {
+		t.Fatalf("%d quests over the pages, want %d", len(paged), len(all.Quests))
+	}
+	for i := range paged {
+		if paged[i].ID != all.Quests[i].ID {
+			t.Errorf("quest %d on the pages is %d, want %d", i, paged[i].ID, all.Quests[i].ID)
+		}
+	}
+
+	completed := s.history("?status=completed&priority=1")
+	if len(completed.Quests) != 1 || completed.Quests[0].Quest.ID != quests.MainQuest.ID {
+		t.Errorf("completed main quests %+v, want the finished one", completed.Quests)
+	}
+
+	// a cursor only pages on with the filter it came from
+	cursor = s.history("?status=active,completed&limit=1").NextCursor
+	if cursor == "" {
+		t.Fatal("no next cursor on the first page")
+	}
+	if code := s.do("GET", "/quests/history?status=completed,active&limit=2&cursor="+cursor, nil); code != http.StatusOK {
+		t.Errorf("the same filter in another order and page size: status %d, want %d", code, http.StatusOK)
+	}
+	for _, query := range []string{"?status=active", "?status=active,completed&priority=1", "?status=active,completed&from=2020-01-01", "?limit=1"} {
+		if code := s.do("GET", "/quests/history"+query+"&cursor="+cursor, nil); code != http.StatusBadRequest {
+			t.Errorf("history%s with another filter's cursor: status %d, want %d", query, code, http.StatusBadRequest)
+		}
+	}
+
+	for _, query := range []string{"?status=done", "?limit=0", "?from=yesterday", "?cursor=nope"} {
+		if code := s.do("GET", "/quests/history"+query, nil); code != http.StatusBadRequest {
+			t.Errorf("history%s: status %d, want %d", query, code, http.StatusBadRequest)
+		}
+	}
+
+	s.player = 999
+	if code := s.do("GET", "/quests/history", nil); code != http.StatusNotFound {
+		t.Errorf("history of an unknown player: status %d, want %d", code, http.StatusNotFound)
+	}
+}
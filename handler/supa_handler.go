@@ -2,15 +2,14 @@ package supa
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
-	"io"
 	"log"
 	"net/http"
-	"os"
 	"sync"
 
-	"github.com/MultiX0/solo_leveling_system/db"
 	"github.com/MultiX0/solo_leveling_system/handler/functions"
+	"github.com/MultiX0/solo_leveling_system/storage"
 	"github.com/MultiX0/solo_leveling_system/types"
 	"github.com/MultiX0/solo_leveling_system/utils"
 	"github.com/gorilla/mux"
@@ -24,13 +23,14 @@ var (
 var wg = &sync.WaitGroup{}
 
 type SupabaseHandler struct {
-	mu sync.RWMutex
+	mu    sync.RWMutex
+	store storage.Storage
 }
 
-func GetSupabaseHandler() *SupabaseHandler {
+func GetSupabaseHandler(store storage.Storage) *SupabaseHandler {
 
 	handlerOnce.Do(func() {
-		handlerInstance = &SupabaseHandler{}
+		handlerInstance = &SupabaseHandler{store: store}
 	})
 
 	return handlerInstance
@@ -40,6 +40,12 @@ func (h *SupabaseHandler) HandleRequests(router *mux.Router) {
 	router.HandleFunc("/init", h.initDB).Methods("POST")
 	router.HandleFunc("/player/{id}", h.GetPlayerByID).Methods("GET")
 	router.HandleFunc("/player", h.CreateNewPlayer).Methods("POST")
+	router.HandleFunc("/player/{id}/stats", h.AllocateStats).Methods("POST")
+	router.HandleFunc("/player/{id}/schedule", h.SetSchedule).Methods("POST")
+	router.HandleFunc("/player/{id}/status", h.GetStatusWindow).Methods("GET")
+	router.HandleFunc("/player/{id}/skills", h.GetSkillProgression).Methods("GET")
+	router.HandleFunc("/player/{id}/skills/tree", h.GetSkillTree).Methods("GET")
+	router.HandleFunc("/player/{id}/skills/{skillId}/use", h.UseSkill).Methods("POST")
 }
 
 func (h *SupabaseHandler) CreateNewPlayer(w http.ResponseWriter, r *http.Request) {
@@ -63,7 +69,7 @@ func (h *SupabaseHandler) CreateNewPlayer(w http.ResponseWriter, r *http.Request
 	}
 
 	player := &types.Player{Name: body.Name, Gender: *body.Gender}
-	player, err := functions.CreateNewPlayer(player)
+	player, err := functions.CreateNewPlayer(h.store, player)
 
 	if err != nil {
 		log.Println(err)
@@ -86,14 +92,21 @@ func (h *SupabaseHandler) GetPlayerByID(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	player, err := functions.GetPlayerByID(playerId)
+	player, err := functions.GetPlayerByID(h.store, playerId)
 	if err != nil {
 		log.Println(err)
 		utils.WriteError(w, http.StatusBadGateway, err)
 		return
 	}
 
-	skills, err := functions.GetPlayerSkills(playerId)
+	skills, err := functions.GetPlayerSkills(h.store, playerId)
+	if err != nil {
+		log.Println(err)
+		utils.WriteError(w, http.StatusBadGateway, err)
+		return
+	}
+
+	streak, err := functions.GetStreak(h.store, playerId)
 	if err != nil {
 		log.Println(err)
 		utils.WriteError(w, http.StatusBadGateway, err)
@@ -101,57 +114,211 @@ func (h *SupabaseHandler) GetPlayerByID(w http.ResponseWriter, r *http.Request)
 	}
 
 	type PlayerDataResponse struct {
-		Player *types.Player  `json:"player"`
-		Skills []*types.Skill `json:"skills"`
+		Player *types.Player `json:"player"`
+		functions.PlayerProgress
+		Skills []*types.Skill    `json:"skills"`
+		Streak *functions.Streak `json:"streak"`
 	}
 
 	response := PlayerDataResponse{
-		Player: player,
-		Skills: skills,
+		Player:         player,
+		PlayerProgress: functions.GetPlayerProgress(player),
+		Skills:         skills,
+		Streak:         streak,
 	}
 
 	utils.WriteJsonResponse(w, http.StatusOK, response)
 
 }
 
-func (h *SupabaseHandler) initDB(w http.ResponseWriter, r *http.Request) {
-	h.mu.Lock()
-	defer h.mu.Unlock()
+func (h *SupabaseHandler) AllocateStats(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	playerId := params["id"]
+	if len(playerId) == 0 {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("please provide valid player id"))
+		return
+	}
+
+	var stats types.Stats
+	if err := json.NewDecoder(r.Body).Decode(&stats); err != nil {
+		log.Println(err)
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("please provide the points to add to each stat such as (strength, agility, sense, vitality, intelligence)"))
+		return
+	}
+
+	player, err := functions.AllocateStats(h.store, playerId, stats)
+	if err != nil {
+		log.Println(err)
+		utils.WriteError(w, allocateStatsStatus(err), err)
+		return
+	}
+
+	utils.WriteJsonResponse(w, http.StatusOK, player)
+}
+
+func allocateStatsStatus(err error) int {
+	switch {
+	case errors.Is(err, functions.ErrInvalidAllocation):
+		return http.StatusBadRequest
+	case errors.Is(err, functions.ErrNotEnoughStatPoints):
+		return http.StatusConflict
+	case errors.Is(err, storage.ErrNotFound):
+		return http.StatusNotFound
+	}
+	return http.StatusBadGateway
+}
+
+func (h *SupabaseHandler) SetSchedule(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	playerId := params["id"]
+	if len(playerId) == 0 {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("please provide valid player id"))
+		return
+	}
+
+	type RequestBody struct {
+		TimeZone  string `json:"time_zone"`
+		ResetHour int    `json:"reset_hour"`
+	}
+
+	var body RequestBody
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		log.Println(err)
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf(`please provide the time zone and reset hour such as {"time_zone": "Europe/Berlin", "reset_hour": 5}`))
+		return
+	}
 
-	questsJson, err := os.Open("quests.json")
+	player, err := functions.SetPlayerSchedule(h.store, playerId, body.TimeZone, body.ResetHour)
+	if err != nil {
+		log.Println(err)
+		utils.WriteError(w, setScheduleStatus(err), err)
+		return
+	}
+
+	utils.WriteJsonResponse(w, http.StatusOK, player)
+}
+
+func setScheduleStatus(err error) int {
+	switch {
+	case errors.Is(err, functions.ErrInvalidTimeZone), errors.Is(err, functions.ErrInvalidResetHour):
+		return http.StatusBadRequest
+	case errors.Is(err, storage.ErrNotFound):
+		return http.StatusNotFound
+	}
+	return http.StatusBadGateway
+}
+
+func (h *SupabaseHandler) GetStatusWindow(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	playerId := params["id"]
+	if len(playerId) == 0 {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("please provide valid player id"))
+		return
+	}
+
+	status, err := functions.GetStatusWindow(h.store, playerId)
 	if err != nil {
 		log.Println(err)
 		utils.WriteError(w, http.StatusBadGateway, err)
+		return
 	}
 
-	defer questsJson.Close()
+	utils.WriteJsonResponse(w, http.StatusOK, status)
+}
 
-	bytesValue, _ := io.ReadAll(questsJson)
-	var quests []types.Quest
+func (h *SupabaseHandler) GetSkillProgression(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	playerId := params["id"]
+	if len(playerId) == 0 {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("please provide valid player id"))
+		return
+	}
 
-	err = json.Unmarshal(bytesValue, &quests)
+	skills, err := functions.GetSkillProgression(h.store, playerId)
 	if err != nil {
 		log.Println(err)
 		utils.WriteError(w, http.StatusBadGateway, err)
+		return
 	}
 
-	h.insertQuests(quests)
+	utils.WriteJsonResponse(w, http.StatusOK, skills)
+}
+
+func (h *SupabaseHandler) GetSkillTree(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	playerId := params["id"]
+	if len(playerId) == 0 {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("please provide valid player id"))
+		return
+	}
 
-	skillsJson, err := os.Open("skills.json")
+	tree, err := functions.GetSkillTree(h.store, playerId)
+	if errors.Is(err, storage.ErrNotFound) {
+		utils.WriteError(w, http.StatusNotFound, fmt.Errorf("player %s not found", playerId))
+		return
+	}
 	if err != nil {
 		log.Println(err)
 		utils.WriteError(w, http.StatusBadGateway, err)
+		return
 	}
 
-	defer skillsJson.Close()
+	utils.WriteJsonResponse(w, http.StatusOK, tree)
+}
+
+func (h *SupabaseHandler) UseSkill(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	playerId := params["id"]
+	skillId := params["skillId"]
+	if len(playerId) == 0 || len(skillId) == 0 {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("please provide valid player and skill ids"))
+		return
+	}
+
+	use, err := functions.UseSkill(h.store, playerId, skillId)
+	if err != nil {
+		log.Println(err)
+		utils.WriteError(w, useSkillStatus(err), err)
+		return
+	}
+
+	utils.WriteJsonResponse(w, http.StatusOK, map[string]any{
+		"message": fmt.Sprintf("%s used!", use.Skill.Name),
+		"use":     use,
+	})
+}
+
+func useSkillStatus(err error) int {
+	switch {
+	case errors.Is(err, functions.ErrSkillNotOwned):
+		return http.StatusNotFound
+	case errors.Is(err, functions.ErrSkillNotActive):
+		return http.StatusBadRequest
+	case errors.Is(err, functions.ErrSkillOnCooldown), errors.Is(err, functions.ErrNotEnoughMana),
+		errors.Is(err, storage.ErrConflict):
+		return http.StatusConflict
+	}
+	return http.StatusBadGateway
+}
+
+func (h *SupabaseHandler) initDB(w http.ResponseWriter, r *http.Request) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	quests, err := storage.ReadQuests("quests.json")
+	if err != nil {
+		log.Println(err)
+		utils.WriteError(w, http.StatusBadGateway, err)
+		return
+	}
 
-	bytesValue, _ = io.ReadAll(skillsJson)
-	var skills []types.Skill
+	h.insertQuests(quests)
 
-	err = json.Unmarshal(bytesValue, &skills)
+	skills, err := storage.ReadSkills("skills.json")
 	if err != nil {
 		log.Println(err)
 		utils.WriteError(w, http.StatusBadGateway, err)
+		return
 	}
 
 	h.insertSkills(skills)
@@ -165,12 +332,17 @@ func (h *SupabaseHandler) insertSkills(skills []types.Skill) {
 		wg.Add(1)
 		go func(skill types.Skill) {
 			defer wg.Done()
-			skills, err := h.getSkillByName(skill.Name)
-			if err != nil || len(skills) != 0 {
+			existing, err := h.store.GetSkillByName(skill.Name)
+			if err == nil {
+				skill.ID = existing.ID
+				h.store.UpdateSkill(&skill)
+				return
+			}
+			if !errors.Is(err, storage.ErrNotFound) {
 				return
 			}
 			log.Println(skill)
-			db.SupabaseClient.From("skills").Insert(map[string]any{"name": skill.Name, "description": skill.Description, "level": skill.Level}, false, "", "", "exact").Execute()
+			h.store.CreateSkill(&skill)
 		}(skill)
 	}
 	wg.Wait()
@@ -181,53 +353,18 @@ func (h *SupabaseHandler) insertQuests(quests []types.Quest) {
 		wg.Add(1)
 		go func(q types.Quest) {
 			defer wg.Done()
-			quest, err := h.getQuestByTitle(q.Title)
-			if err != nil || len(quest) != 0 {
+			existing, err := h.store.GetQuestByTitle(q.Title)
+			if err == nil {
+				q.ID = existing.ID
+				h.store.UpdateQuest(&q)
+				return
+			}
+			if !errors.Is(err, storage.ErrNotFound) {
 				return
 			}
 			log.Println(q)
-			db.SupabaseClient.From("quests").Insert(map[string]any{
-				"title":       q.Title,
-				"description": q.Description,
-				"priority":    q.Priority,
-			}, false, "", "", "exact").Execute()
+			h.store.CreateQuest(&q)
 		}(quest)
 	}
 	wg.Wait()
 }
-
-func (h *SupabaseHandler) getQuestByTitle(title string) ([]types.Quest, error) {
-
-	data, _, err := db.SupabaseClient.From("quests").Select("title", "exact", false).Eq("title", title).Execute()
-	if err != nil {
-		return nil, err
-	}
-
-	var q []types.Quest
-	err = json.Unmarshal(data, &q)
-
-	if err != nil {
-		return nil, err
-	}
-
-	return q, nil
-
-}
-
-func (h *SupabaseHandler) getSkillByName(name string) ([]types.Skill, error) {
-
-	data, _, err := db.SupabaseClient.From("skills").Select("name", "exact", false).Eq("name", name).Execute()
-	if err != nil {
-		return nil, err
-	}
-
-	var s []types.Skill
-	err = json.Unmarshal(data, &s)
-
-	if err != nil {
-		return nil, err
-	}
-
-	return s, nil
-
-}
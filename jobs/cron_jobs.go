@@ -4,16 +4,28 @@ import (
 	"log"
 
 	"github.com/MultiX0/solo_leveling_system/handler/functions"
+	"github.com/MultiX0/solo_leveling_system/storage"
 	"github.com/robfig/cron/v3"
 )
 
-func InitCronJobs() {
+func InitCronJobs(store storage.Storage) {
 	c := cron.New()
-	c.AddFunc("@every 00h01m00s", QuestsJob)
+	c.AddFunc("@every 00h01m00s", func() { QuestsJob(store) })
+	c.AddFunc("@hourly", func() { TriggersJob(store) })
+	c.Start()
 }
 
-func QuestsJob() {
-	err := functions.UpdateOutdatedQuests()
+func QuestsJob(store storage.Storage) {
+	err := functions.UpdateOutdatedQuests(store)
+	if err != nil {
+		log.Println(err)
+	}
+}
+
+// TriggersJob hands out the emergency and hidden quests whose triggers
+// fire.
+func TriggersJob(store storage.Storage) {
+	err := functions.FireTriggers(store)
 	if err != nil {
 		log.Println(err)
 	}
@@ -2,22 +2,38 @@ package main
 
 import (
 	"log"
+	"os"
 
 	"github.com/MultiX0/solo_leveling_system/api"
 	"github.com/MultiX0/solo_leveling_system/db"
+	"github.com/MultiX0/solo_leveling_system/handler/functions"
 	"github.com/MultiX0/solo_leveling_system/jobs"
 	"github.com/joho/godotenv"
 )
 
 func main() {
 
+	// .env is optional, STORAGE=memory runs without any credentials
 	if err := godotenv.Load(); err != nil {
-		log.Fatal(err)
+		log.Println(err)
+	}
+
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		version, err := db.Migrate()
+		if err != nil {
+			log.Fatal(err)
+		}
+		log.Println("schema is at version", version)
+		return
 	}
 
-	db.InitDB()
-	jobs.InitCronJobs()
+	functions.InitProgression()
+	store := db.InitDB()
+	if err := functions.InitRolls(store); err != nil {
+		log.Fatal(err)
+	}
+	jobs.InitCronJobs(store)
 
-	server := api.NewServer(":8080")
+	server := api.NewServer(":8080", store)
 	server.RunServer()
 }
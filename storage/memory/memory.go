@@ -0,0 +1,818 @@
+package memory
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/MultiX0/solo_leveling_system/storage"
+	"github.com/MultiX0/solo_leveling_system/types"
+)
+
+// Store keeps every table in process memory. It enforces the same
+// constraints as the schema in the README so code that works against it
+// behaves the same against a real database.
+type Store struct {
+	mu *sync.RWMutex
+	// inTx is set on the view handed to Atomic callbacks, which already
+	// hold mu for the whole transaction.
+	inTx bool
+
+	*tables
+}
+
+type tables struct {
+	players      map[int]*types.Player
+	quests       map[int]*types.Quest
+	skills       map[int]*types.Skill
+	playerQuests map[int]*types.PlayerQuest
+	playerSkills map[int]*types.PlayerSkills
+	skillUsages  map[int]*types.SkillUsage
+	playerChains map[int]*types.PlayerChain
+	settings     map[string]string
+
+	seq map[string]int
+}
+
+func New() *Store {
+	return &Store{
+		mu: &sync.RWMutex{},
+		tables: &tables{
+			players:      make(map[int]*types.Player),
+			quests:       make(map[int]*types.Quest),
+			skills:       make(map[int]*types.Skill),
+			playerQuests: make(map[int]*types.PlayerQuest),
+			playerSkills: make(map[int]*types.PlayerSkills),
+			skillUsages:  make(map[int]*types.SkillUsage),
+			playerChains: make(map[int]*types.PlayerChain),
+			settings:     make(map[string]string),
+			seq:          make(map[string]int),
+		},
+	}
+}
+
+func (s *Store) lock() func() {
+	if s.inTx {
+		return func() {}
+	}
+	s.mu.Lock()
+	return s.mu.Unlock
+}
+
+func (s *Store) rlock() func() {
+	if s.inTx {
+		return func() {}
+	}
+	s.mu.RLock()
+	return s.mu.RUnlock
+}
+
+// Atomic holds the write lock for the whole of fn and restores a copy of
+// every table if fn fails.
+func (s *Store) Atomic(fn func(tx storage.Storage) error) error {
+	if s.inTx {
+		return fn(s)
+	}
+
+	defer s.lock()()
+
+	backup := s.tables.clone()
+	if err := fn(&Store{mu: s.mu, inTx: true, tables: s.tables}); err != nil {
+		*s.tables = *backup
+		return err
+	}
+
+	return nil
+}
+
+func (t *tables) clone() *tables {
+	c := &tables{
+		players:      cloneTable(t.players),
+		quests:       cloneTable(t.quests),
+		skills:       cloneTable(t.skills),
+		playerQuests: cloneTable(t.playerQuests),
+		playerSkills: cloneTable(t.playerSkills),
+		skillUsages:  cloneTable(t.skillUsages),
+		playerChains: cloneTable(t.playerChains),
+		settings:     make(map[string]string, len(t.settings)),
+		seq:          make(map[string]int, len(t.seq)),
+	}
+	for k, v := range t.settings {
+		c.settings[k] = v
+	}
+	for k, v := range t.seq {
+		c.seq[k] = v
+	}
+	return c
+}
+
+func cloneTable[T any](m map[int]*T) map[int]*T {
+	c := make(map[int]*T, len(m))
+	for k, v := range m {
+		row := *v
+		c[k] = &row
+	}
+	return c
+}
+
+func (s *Store) nextID(table string) int {
+	s.seq[table]++
+	return s.seq[table]
+}
+
+func (s *Store) CreatePlayer(player *types.Player) (*types.Player, error) {
+	defer s.lock()()
+
+	newPlayer := *player
+	newPlayer.ID = s.nextID("players")
+	newPlayer.JoinedAt = time.Now().UTC()
+	newPlayer.XP = 0
+	newPlayer.Stats = types.Stats{
+		Strength:     types.BaseStat,
+		Agility:      types.BaseStat,
+		Sense:        types.BaseStat,
+		Vitality:     types.BaseStat,
+		Intelligence: types.BaseStat,
+	}
+	newPlayer.StatPoints = 0
+	newPlayer.MaxLevel = 1
+	newPlayer.TimeZone = types.DefaultTimeZone
+	newPlayer.ResetHour = 0
+	s.players[newPlayer.ID] = &newPlayer
+
+	res := newPlayer
+	return &res, nil
+}
+
+func (s *Store) GetPlayer(id int) (*types.Player, error) {
+	defer s.rlock()()
+
+	player, ok := s.players[id]
+	if !ok {
+		return nil, storage.ErrNotFound
+	}
+
+	res := *player
+	return &res, nil
+}
+
+func (s *Store) ListPlayers() ([]*types.Player, error) {
+	defer s.rlock()()
+
+	players := []*types.Player{}
+	for _, id := range sortedKeys(s.players) {
+		res := *s.players[id]
+		players = append(players, &res)
+	}
+
+	return players, nil
+}
+
+func (s *Store) AddPlayerXP(id int, delta int) (*types.Player, error) {
+	defer s.lock()()
+
+	player, ok := s.players[id]
+	if !ok {
+		return nil, storage.ErrNotFound
+	}
+
+	player.XP = max(player.XP+delta, 0)
+
+	res := *player
+	return &res, nil
+}
+
+func (s *Store) AddPlayerStats(id int, stats types.Stats, statPoints int) (*types.Player, error) {
+	defer s.lock()()
+
+	player, ok := s.players[id]
+	if !ok {
+		return nil, storage.ErrNotFound
+	}
+
+	if player.StatPoints+statPoints < 0 {
+		return nil, storage.ErrConstraint
+	}
+
+	player.Strength += stats.Strength
+	player.Agility += stats.Agility
+	player.Sense += stats.Sense
+	player.Vitality += stats.Vitality
+	player.Intelligence += stats.Intelligence
+	player.StatPoints += statPoints
+
+	res := *player
+	return &res, nil
+}
+
+func (s *Store) LevelUpPlayer(id int, from, to int, statPoints int) (bool, error) {
+	defer s.lock()()
+
+	player, ok := s.players[id]
+	if !ok {
+		return false, storage.ErrNotFound
+	}
+
+	if player.MaxLevel != from {
+		return false, nil
+	}
+
+	player.MaxLevel = to
+	player.StatPoints += statPoints
+
+	return true, nil
+}
+
+func (s *Store) SpendPlayerMana(id int, fromMana int, fromAt *time.Time, mana int, at time.Time) (bool, error) {
+	defer s.lock()()
+
+	player, ok := s.players[id]
+	if !ok {
+		return false, storage.ErrNotFound
+	}
+
+	if player.Mana != fromMana || (player.ManaUpdatedAt == nil) != (fromAt == nil) ||
+		(fromAt != nil && !player.ManaUpdatedAt.Equal(*fromAt)) {
+		return false, nil
+	}
+
+	at = at.UTC()
+	player.Mana = mana
+	player.ManaUpdatedAt = &at
+
+	return true, nil
+}
+
+func (s *Store) RecordPlayerStreak(id int, streak int) error {
+	defer s.lock()()
+
+	player, ok := s.players[id]
+	if !ok {
+		return storage.ErrNotFound
+	}
+
+	player.LongestStreak = max(player.LongestStreak, streak)
+
+	return nil
+}
+
+func (s *Store) UpdatePlayerTitle(id int, title string) error {
+	defer s.lock()()
+
+	player, ok := s.players[id]
+	if !ok {
+		return storage.ErrNotFound
+	}
+
+	player.Title = title
+
+	return nil
+}
+
+func (s *Store) UpdatePlayerSchedule(id int, schedule types.Schedule) error {
+	defer s.lock()()
+
+	player, ok := s.players[id]
+	if !ok {
+		return storage.ErrNotFound
+	}
+
+	if schedule.ResetHour < 0 || schedule.ResetHour > 23 {
+		return storage.ErrConstraint
+	}
+
+	if schedule.ScheduleFrom != nil {
+		from := schedule.ScheduleFrom.UTC()
+		schedule.ScheduleFrom = &from
+	}
+	player.Schedule = schedule
+
+	return nil
+}
+
+func (s *Store) CreateQuest(quest *types.Quest) (*types.Quest, error) {
+	defer s.lock()()
+
+	if _, ok := s.players[quest.Owner]; quest.Owner != 0 && !ok {
+		return nil, storage.ErrConstraint
+	}
+
+	newQuest := *quest
+	newQuest.ID = s.nextID("quests")
+	newQuest.Archived = false
+	if newQuest.Cadence == "" {
+		newQuest.Cadence = types.CadenceDaily
+	}
+	if newQuest.Weight <= 0 {
+		newQuest.Weight = types.DefaultQuestWeight
+	}
+	s.quests[newQuest.ID] = &newQuest
+
+	res := newQuest
+	return &res, nil
+}
+
+func (s *Store) UpdateQuest(quest *types.Quest) error {
+	defer s.lock()()
+
+	if _, ok := s.quests[quest.ID]; !ok {
+		return storage.ErrNotFound
+	}
+
+	updated := *quest
+	if updated.Cadence == "" {
+		updated.Cadence = types.CadenceDaily
+	}
+	if updated.Weight <= 0 {
+		updated.Weight = types.DefaultQuestWeight
+	}
+	updated.Owner = s.quests[quest.ID].Owner
+	updated.Archived = s.quests[quest.ID].Archived
+	s.quests[quest.ID] = &updated
+
+	return nil
+}
+
+func (s *Store) GetQuest(id int) (*types.Quest, error) {
+	defer s.rlock()()
+
+	quest, ok := s.quests[id]
+	if !ok {
+		return nil, storage.ErrNotFound
+	}
+
+	res := *quest
+	return &res, nil
+}
+
+func (s *Store) GetQuestByTitle(title string) (*types.Quest, error) {
+	defer s.rlock()()
+
+	for _, id := range sortedKeys(s.quests) {
+		if s.quests[id].Title == title && s.quests[id].Owner == 0 {
+			res := *s.quests[id]
+			return &res, nil
+		}
+	}
+
+	return nil, storage.ErrNotFound
+}
+
+func (s *Store) ListQuests(kind storage.QuestKind) ([]types.Quest, error) {
+	return s.listQuests(0, kind)
+}
+
+func (s *Store) ListCustomQuests(ownerID int, kind storage.QuestKind) ([]types.Quest, error) {
+	return s.listQuests(ownerID, kind)
+}
+
+func (s *Store) listQuests(ownerID int, kind storage.QuestKind) ([]types.Quest, error) {
+	defer s.rlock()()
+
+	var quests []types.Quest
+	for _, id := range sortedKeys(s.quests) {
+		quest := s.quests[id]
+		if quest.Owner == ownerID && !quest.Archived && matchKind(kind, quest.Priority, quest.Cadence) {
+			quests = append(quests, *quest)
+		}
+	}
+
+	return quests, nil
+}
+
+func (s *Store) ArchiveQuest(id int) error {
+	defer s.lock()()
+
+	quest, ok := s.quests[id]
+	if !ok {
+		return storage.ErrNotFound
+	}
+
+	quest.Archived = true
+
+	return nil
+}
+
+func (s *Store) CreateSkill(skill *types.Skill) (*types.Skill, error) {
+	defer s.lock()()
+
+	newSkill := *skill
+	newSkill.ID = s.nextID("skills")
+	if newSkill.Type == "" {
+		newSkill.Type = types.SkillPassive
+	}
+	s.skills[newSkill.ID] = &newSkill
+
+	res := newSkill
+	return &res, nil
+}
+
+func (s *Store) GetSkill(id int) (*types.Skill, error) {
+	defer s.rlock()()
+
+	skill, ok := s.skills[id]
+	if !ok {
+		return nil, storage.ErrNotFound
+	}
+
+	res := *skill
+	return &res, nil
+}
+
+func (s *Store) GetSkillByName(name string) (*types.Skill, error) {
+	defer s.rlock()()
+
+	for _, id := range sortedKeys(s.skills) {
+		if s.skills[id].Name == name {
+			res := *s.skills[id]
+			return &res, nil
+		}
+	}
+
+	return nil, storage.ErrNotFound
+}
+
+func (s *Store) ListSkills() ([]*types.Skill, error) {
+	defer s.rlock()()
+
+	var skills []*types.Skill
+	for _, id := range sortedKeys(s.skills) {
+		skill := *s.skills[id]
+		skills = append(skills, &skill)
+	}
+
+	return skills, nil
+}
+
+func (s *Store) UpdateSkill(skill *types.Skill) error {
+	defer s.lock()()
+
+	if _, ok := s.skills[skill.ID]; !ok {
+		return storage.ErrNotFound
+	}
+
+	updated := *skill
+	s.skills[skill.ID] = &updated
+
+	return nil
+}
+
+func validStatus(status int) bool {
+	return status >= types.QuestAbandoned && status <= types.QuestExpired
+}
+
+func (s *Store) CreatePlayerQuest(pq *types.PlayerQuest) (*types.PlayerQuest, error) {
+	defer s.lock()()
+
+	if _, ok := s.players[pq.PlayerID]; !ok {
+		return nil, storage.ErrConstraint
+	}
+	if _, ok := s.quests[pq.QuestID]; !ok {
+		return nil, storage.ErrConstraint
+	}
+	if !validStatus(pq.Status) {
+		return nil, storage.ErrConstraint
+	}
+	if _, ok := s.playerQuests[pq.RerolledFrom]; pq.RerolledFrom != 0 && !ok {
+		return nil, storage.ErrConstraint
+	}
+
+	newPQ := *pq
+	newPQ.ID = s.nextID("player_quests")
+	if newPQ.StartAt.IsZero() {
+		newPQ.StartAt = time.Now()
+	}
+	newPQ.StartAt = newPQ.StartAt.UTC()
+	if newPQ.Deadline.IsZero() {
+		newPQ.Deadline = newPQ.StartAt.Add(types.DefaultQuestDeadline)
+	}
+	newPQ.Deadline = newPQ.Deadline.UTC()
+	if newPQ.Cadence == "" {
+		newPQ.Cadence = types.CadenceDaily
+	}
+	s.playerQuests[newPQ.ID] = &newPQ
+
+	res := newPQ
+	return &res, nil
+}
+
+func (s *Store) ListPlayerQuests(filter storage.PlayerQuestFilter) ([]*types.PlayerQuest, error) {
+	defer s.rlock()()
+
+	var quests []*types.PlayerQuest
+	for _, pq := range s.playerQuests {
+		if matchPlayerQuest(filter, pq) {
+			res := *pq
+			quests = append(quests, &res)
+		}
+	}
+
+	sort.Slice(quests, func(i, j int) bool {
+		if quests[i].StartAt.Equal(quests[j].StartAt) {
+			return quests[i].ID > quests[j].ID
+		}
+		return quests[i].StartAt.After(quests[j].StartAt)
+	})
+
+	if filter.Limit > 0 && len(quests) > filter.Limit {
+		quests = quests[:filter.Limit]
+	}
+
+	return quests, nil
+}
+
+func (s *Store) CountPlayerQuests(filter storage.PlayerQuestFilter) (map[int]int, error) {
+	defer s.rlock()()
+
+	filter.After = nil
+	counts := map[int]int{}
+	for _, pq := range s.playerQuests {
+		if matchPlayerQuest(filter, pq) {
+			counts[pq.Status]++
+		}
+	}
+
+	return counts, nil
+}
+
+func (s *Store) UpdatePlayerQuestStatus(filter storage.PlayerQuestFilter, status int) (int, error) {
+	defer s.lock()()
+
+	if !validStatus(status) {
+		return 0, storage.ErrConstraint
+	}
+
+	count := 0
+	for _, pq := range s.playerQuests {
+		if matchPlayerQuest(filter, pq) {
+			pq.Status = status
+			count++
+		}
+	}
+
+	return count, nil
+}
+
+func (s *Store) UpdatePlayerQuestProgress(id int, progress []float64) error {
+	defer s.lock()()
+
+	pq, ok := s.playerQuests[id]
+	if !ok {
+		return storage.ErrNotFound
+	}
+
+	pq.Progress = append([]float64(nil), progress...)
+
+	return nil
+}
+
+func (s *Store) CreatePlayerSkill(ps *types.PlayerSkills) (*types.PlayerSkills, error) {
+	defer s.lock()()
+
+	if _, ok := s.players[ps.PlayerID]; !ok {
+		return nil, storage.ErrConstraint
+	}
+	if _, ok := s.skills[ps.SkillID]; !ok {
+		return nil, storage.ErrConstraint
+	}
+	for _, owned := range s.playerSkills {
+		if owned.PlayerID == ps.PlayerID && owned.SkillID == ps.SkillID {
+			return nil, storage.ErrConflict
+		}
+	}
+
+	newPS := *ps
+	newPS.ID = s.nextID("player_skills")
+	newPS.RecivedAt = time.Now().UTC()
+	newPS.Level = max(newPS.Level, 1)
+	s.playerSkills[newPS.ID] = &newPS
+
+	res := newPS
+	return &res, nil
+}
+
+func (s *Store) UpdatePlayerSkillProgress(id int, level, xp int) error {
+	defer s.lock()()
+
+	ps, ok := s.playerSkills[id]
+	if !ok {
+		return storage.ErrNotFound
+	}
+
+	ps.Level = level
+	ps.XP = xp
+
+	return nil
+}
+
+func (s *Store) listPlayerSkills(match func(*types.PlayerSkills) bool) []*types.PlayerSkills {
+	defer s.rlock()()
+
+	var skills []*types.PlayerSkills
+	for _, id := range sortedKeys(s.playerSkills) {
+		if match(s.playerSkills[id]) {
+			ps := *s.playerSkills[id]
+			skills = append(skills, &ps)
+		}
+	}
+
+	return skills
+}
+
+func (s *Store) ListPlayerSkills(playerID int) ([]*types.PlayerSkills, error) {
+	return s.listPlayerSkills(func(ps *types.PlayerSkills) bool {
+		return ps.PlayerID == playerID
+	}), nil
+}
+
+func (s *Store) GetPlayerSkill(playerID, skillID int) (*types.PlayerSkills, error) {
+	skills := s.listPlayerSkills(func(ps *types.PlayerSkills) bool {
+		return ps.PlayerID == playerID && ps.SkillID == skillID
+	})
+
+	if len(skills) == 0 {
+		return nil, storage.ErrNotFound
+	}
+
+	return skills[0], nil
+}
+
+func sortedKeys[T any](m map[int]T) []int {
+	keys := make([]int, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Ints(keys)
+	return keys
+}
+
+func matchKind(kind storage.QuestKind, priority int, cadence string) bool {
+	switch kind {
+	case storage.MainQuests:
+		return priority == 1 && cadence == types.CadenceDaily
+	case storage.SideQuests:
+		return priority > 1 && cadence == types.CadenceDaily
+	case storage.PenaltyQuests:
+		return priority == types.PenaltyPriority && cadence == types.CadenceDaily
+	case storage.WeeklyQuests:
+		return cadence == types.CadenceWeekly
+	case storage.MonthlyQuests:
+		return cadence == types.CadenceMonthly
+	case storage.EventQuests:
+		return cadence == types.CadenceEvent
+	}
+	return true
+}
+
+func matchPlayerQuest(f storage.PlayerQuestFilter, pq *types.PlayerQuest) bool {
+	if f.ID != 0 && pq.ID != f.ID {
+		return false
+	}
+	if f.PlayerID != 0 && pq.PlayerID != f.PlayerID {
+		return false
+	}
+	if f.QuestID != 0 && pq.QuestID != f.QuestID {
+		return false
+	}
+	if len(f.Status) > 0 {
+		found := false
+		for _, status := range f.Status {
+			if pq.Status == status {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	if len(f.Priority) > 0 {
+		found := false
+		for _, priority := range f.Priority {
+			if pq.Priority == priority {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	if !matchKind(f.Kind, pq.Priority, pq.Cadence) {
+		return false
+	}
+	if !f.StartedBefore.IsZero() && !pq.StartAt.Before(f.StartedBefore) {
+		return false
+	}
+	if !f.StartedAfter.IsZero() && pq.StartAt.Before(f.StartedAfter) {
+		return false
+	}
+	if !f.DeadlineBefore.IsZero() && !pq.Deadline.Before(f.DeadlineBefore) {
+		return false
+	}
+	if f.After != nil && !pq.StartAt.Before(f.After.StartAt) &&
+		(!pq.StartAt.Equal(f.After.StartAt) || pq.ID >= f.After.ID) {
+		return false
+	}
+	return true
+}
+
+func (s *Store) CreateSkillUsage(usage *types.SkillUsage) (*types.SkillUsage, error) {
+	defer s.lock()()
+
+	if _, ok := s.players[usage.PlayerID]; !ok {
+		return nil, storage.ErrConstraint
+	}
+	if _, ok := s.skills[usage.SkillID]; !ok {
+		return nil, storage.ErrConstraint
+	}
+
+	newUsage := *usage
+	newUsage.ID = s.nextID("skill_usages")
+	if newUsage.UsedAt.IsZero() {
+		newUsage.UsedAt = time.Now()
+	}
+	newUsage.UsedAt = newUsage.UsedAt.UTC()
+	s.skillUsages[newUsage.ID] = &newUsage
+
+	res := newUsage
+	return &res, nil
+}
+
+func (s *Store) LastSkillUsage(playerID, skillID int) (*types.SkillUsage, error) {
+	defer s.rlock()()
+
+	var last *types.SkillUsage
+	for _, id := range sortedKeys(s.skillUsages) {
+		usage := s.skillUsages[id]
+		if usage.PlayerID != playerID || usage.SkillID != skillID {
+			continue
+		}
+		if last == nil || !usage.UsedAt.Before(last.UsedAt) {
+			last = usage
+		}
+	}
+
+	if last == nil {
+		return nil, storage.ErrNotFound
+	}
+
+	res := *last
+	return &res, nil
+}
+
+func (s *Store) ListPlayerChains(playerID int) ([]*types.PlayerChain, error) {
+	defer s.rlock()()
+
+	var chains []*types.PlayerChain
+	for _, id := range sortedKeys(s.playerChains) {
+		if s.playerChains[id].PlayerID == playerID {
+			res := *s.playerChains[id]
+			chains = append(chains, &res)
+		}
+	}
+
+	sort.Slice(chains, func(i, j int) bool { return chains[i].Chain < chains[j].Chain })
+
+	return chains, nil
+}
+
+func (s *Store) AdvancePlayerChain(playerID int, chain string, step int) error {
+	defer s.lock()()
+
+	if _, ok := s.players[playerID]; !ok {
+		return storage.ErrConstraint
+	}
+
+	for _, pc := range s.playerChains {
+		if pc.PlayerID == playerID && pc.Chain == chain {
+			if pc.Step < step {
+				pc.Step = step
+				pc.UpdatedAt = time.Now().UTC()
+			}
+			return nil
+		}
+	}
+
+	id := s.nextID("player_chains")
+	s.playerChains[id] = &types.PlayerChain{
+		ID:        id,
+		PlayerID:  playerID,
+		Chain:     chain,
+		Step:      step,
+		UpdatedAt: time.Now().UTC(),
+	}
+
+	return nil
+}
+
+func (s *Store) InitSetting(name, value string) (string, error) {
+	defer s.lock()()
+
+	if stored, ok := s.settings[name]; ok {
+		return stored, nil
+	}
+	s.settings[name] = value
+
+	return value, nil
+}
@@ -0,0 +1,48 @@
+-- every migration only adds what is missing, so they also bring a Supabase
+-- database made from the README schema up to date
+create table if not exists players (
+    id bigint generated by default as identity primary key,
+    name text null,
+    gender boolean null,
+    joined_at timestamp with time zone not null default (now() at time zone 'utc'::text)
+);
+
+create table if not exists quests (
+    id bigint generated by default as identity primary key,
+    description text null,
+    title text null,
+    priority smallint null
+);
+create index if not exists quests_priority_idx on quests using btree (priority);
+create index if not exists quests_title_idx on quests using btree (title);
+
+create table if not exists skills (
+    id bigint generated by default as identity primary key,
+    name text null,
+    description text null,
+    level integer null
+);
+create index if not exists skills_level_idx on skills using btree (level);
+create index if not exists skills_name_idx on skills using btree (name);
+
+create table if not exists player_quests (
+    id bigint generated by default as identity primary key,
+    start_at timestamp with time zone not null,
+    player bigint null references players (id) on update cascade on delete cascade,
+    quest bigint null references quests (id) on update cascade on delete cascade,
+    status integer not null default 0,
+    priority integer not null,
+    constraint player_quests_finish_check check (status >= -1 and status <= 2)
+);
+create index if not exists player_quests_player_idx on player_quests using btree (player, start_at desc);
+-- tables made before quests could expire or be abandoned only allow 0 and 1
+alter table player_quests drop constraint if exists player_quests_finish_check;
+alter table player_quests add constraint player_quests_finish_check check (status >= -1 and status <= 2);
+
+create table if not exists player_skills (
+    id bigint generated by default as identity primary key,
+    recived_at timestamp with time zone not null default (now() at time zone 'utc'::text),
+    skill bigint not null references skills (id) on update cascade on delete cascade,
+    player bigint not null references players (id) on update cascade on delete cascade,
+    constraint player_skills_skill_key unique (skill)
+);
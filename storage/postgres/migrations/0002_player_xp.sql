@@ -0,0 +1 @@
+alter table players add column if not exists xp integer not null default 0;
@@ -0,0 +1,8 @@
+alter table players add column if not exists strength integer not null default 10;
+alter table players add column if not exists agility integer not null default 10;
+alter table players add column if not exists sense integer not null default 10;
+alter table players add column if not exists vitality integer not null default 10;
+alter table players add column if not exists intelligence integer not null default 10;
+alter table players add column if not exists stat_points integer not null default 0 check (stat_points >= 0);
+alter table players add column if not exists max_level integer not null default 1;
+alter table players add column if not exists title text null;
@@ -0,0 +1,4 @@
+-- skills are owned per player, every player can earn the whole catalogue
+alter table player_skills drop constraint if exists player_skills_skill_key;
+alter table player_skills drop constraint if exists player_skills_player_skill_key;
+alter table player_skills add constraint player_skills_player_skill_key unique (player, skill);
@@ -0,0 +1,3 @@
+alter table skills add column if not exists max_level integer not null default 10;
+alter table player_skills add column if not exists level integer not null default 1;
+alter table player_skills add column if not exists xp integer not null default 0;
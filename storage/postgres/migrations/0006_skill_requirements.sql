@@ -0,0 +1 @@
+alter table skills add column if not exists requires jsonb null;
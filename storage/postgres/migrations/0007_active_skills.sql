@@ -0,0 +1,15 @@
+alter table skills add column if not exists type text not null default 'passive';
+alter table skills add column if not exists cooldown integer not null default 0;
+alter table skills add column if not exists mana_cost integer not null default 0;
+
+alter table players add column if not exists mana integer not null default 0;
+alter table players add column if not exists mana_updated_at timestamp with time zone null;
+
+create table if not exists skill_usages (
+    id bigint generated by default as identity primary key,
+    player bigint not null references players (id) on update cascade on delete cascade,
+    skill bigint not null references skills (id) on update cascade on delete cascade,
+    used_at timestamp with time zone not null,
+    mana_cost integer not null default 0
+);
+create index if not exists skill_usages_player_skill_idx on skill_usages using btree (player, skill, used_at desc);
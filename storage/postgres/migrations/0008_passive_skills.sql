@@ -0,0 +1,5 @@
+alter table skills add column if not exists effects jsonb null;
+
+alter table player_quests add column if not exists deadline timestamp with time zone null;
+update player_quests set deadline = start_at + interval '24 hours' where deadline is null;
+create index if not exists player_quests_deadline_idx on player_quests using btree (status, deadline);
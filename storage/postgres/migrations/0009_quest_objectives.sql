@@ -0,0 +1,3 @@
+alter table quests add column if not exists objectives jsonb null;
+
+alter table player_quests add column if not exists progress jsonb null;
@@ -0,0 +1,2 @@
+alter table player_quests add column if not exists description text null;
+alter table player_quests add column if not exists objectives jsonb null;
@@ -0,0 +1 @@
+alter table players add column if not exists longest_streak integer not null default 0;
@@ -0,0 +1,2 @@
+alter table quests add column if not exists cadence text not null default 'daily';
+alter table player_quests add column if not exists cadence text not null default 'daily';
@@ -0,0 +1,11 @@
+alter table quests add column if not exists chain text null;
+alter table quests add column if not exists chain_step integer not null default 0;
+
+create table if not exists player_chains (
+    id bigint generated by default as identity primary key,
+    player bigint not null references players (id) on update cascade on delete cascade,
+    chain text not null,
+    step integer not null default 0,
+    updated_at timestamp with time zone not null,
+    unique (player, chain)
+);
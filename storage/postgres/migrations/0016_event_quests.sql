@@ -0,0 +1 @@
+alter table quests add column if not exists trigger jsonb null;
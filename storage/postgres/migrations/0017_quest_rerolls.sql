@@ -0,0 +1 @@
+alter table player_quests add column if not exists rerolled_from bigint null references player_quests (id) on update cascade on delete set null;
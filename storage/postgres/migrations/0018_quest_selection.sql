@@ -0,0 +1,2 @@
+alter table quests add column if not exists category text null;
+alter table quests add column if not exists weight integer not null default 10;
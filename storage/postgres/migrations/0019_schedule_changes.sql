@@ -0,0 +1,3 @@
+alter table players add column if not exists schedule_from timestamp with time zone null;
+alter table players add column if not exists previous_time_zone text null;
+alter table players add column if not exists previous_reset_hour integer null check (previous_reset_hour >= 0 and previous_reset_hour <= 23);
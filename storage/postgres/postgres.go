@@ -0,0 +1,77 @@
+package postgres
+
+import (
+	"database/sql"
+	"embed"
+	"errors"
+	"io/fs"
+
+	"github.com/MultiX0/solo_leveling_system/storage"
+	"github.com/MultiX0/solo_leveling_system/storage/sqlstore"
+	"github.com/lib/pq"
+)
+
+//go:embed migrations/*.sql
+var migrations embed.FS
+
+var Dialect = sqlstore.Dialect{
+	Rebind:   sqlstore.RebindDollar,
+	MapError: mapError,
+}
+
+// Open connects to the database at dsn (a postgres:// URL or key=value
+// string) and applies any pending migrations.
+func Open(dsn string) (*sqlstore.Store, error) {
+	db, err := Connect(dsn)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err = Migrate(db); err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return sqlstore.New(db, Dialect), nil
+}
+
+// Connect opens and checks the connection without touching the schema.
+func Connect(dsn string) (*sql.DB, error) {
+	db, err := sql.Open("postgres", dsn)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = db.Ping(); err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return db, nil
+}
+
+// Migrate brings the schema up to date and returns the applied version.
+func Migrate(db *sql.DB) (int, error) {
+	scripts, err := fs.Sub(migrations, "migrations")
+	if err != nil {
+		return 0, err
+	}
+
+	return sqlstore.Migrate(db, Dialect, scripts)
+}
+
+func mapError(err error) error {
+	var pqErr *pq.Error
+	if !errors.As(err, &pqErr) {
+		return err
+	}
+
+	switch pqErr.Code {
+	case "23505":
+		return storage.ErrConflict
+	case "23503", "23514":
+		return storage.ErrConstraint
+	}
+
+	return err
+}
@@ -0,0 +1,118 @@
+package storage
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/MultiX0/solo_leveling_system/types"
+)
+
+func ReadQuests(path string) ([]types.Quest, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var quests []types.Quest
+	if err = json.Unmarshal(data, &quests); err != nil {
+		return nil, err
+	}
+
+	for i, quest := range quests {
+		switch quest.Cadence {
+		case "":
+			quests[i].Cadence = types.CadenceDaily
+		case types.CadenceDaily, types.CadenceWeekly, types.CadenceMonthly, types.CadenceEvent:
+		default:
+			return nil, fmt.Errorf("quest %q has an unknown cadence %q", quest.Title, quest.Cadence)
+		}
+
+		if quest.Weight < 0 {
+			return nil, fmt.Errorf("quest %q has a negative weight", quest.Title)
+		}
+
+		// chains are handed out as daily side quests
+		if (quest.Chain == "") != (quest.ChainStep == 0) || quest.ChainStep < 0 ||
+			(quest.Chain != "" && (quest.Priority < 2 || quest.Cadence != "" && quest.Cadence != types.CadenceDaily)) {
+			return nil, fmt.Errorf("quest %q must be a daily side quest with both a chain and a chain_step from 1", quest.Title)
+		}
+
+		if (quest.Cadence == types.CadenceEvent) != (quest.Trigger != nil) {
+			return nil, fmt.Errorf("quest %q needs both the event cadence and a trigger", quest.Title)
+		}
+		if trigger := quest.Trigger; trigger != nil &&
+			(trigger.Type != types.TriggerEmergency && trigger.Type != types.TriggerHidden ||
+				trigger.Hours <= 0 || trigger.Chance < 0 || trigger.Chance > 1 || quest.Priority < 1) {
+			return nil, fmt.Errorf("quest %q needs an emergency or hidden trigger with hours, a chance from 0 to 1 and a priority from 1", quest.Title)
+		}
+	}
+
+	return quests, nil
+}
+
+func ReadSkills(path string) ([]types.Skill, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var skills []types.Skill
+	if err = json.Unmarshal(data, &skills); err != nil {
+		return nil, err
+	}
+
+	for i := range skills {
+		if skills[i].MaxLevel == 0 {
+			skills[i].MaxLevel = types.DefaultSkillMaxLevel
+		}
+		if skills[i].Type == "" {
+			skills[i].Type = types.SkillPassive
+		}
+	}
+
+	return skills, nil
+}
+
+// Seed inserts the catalogue entries the store doesn't know yet and
+// refreshes the ones it already has. Quests are matched by title and
+// skills by name, so it is safe to run twice.
+func Seed(store Storage, quests []types.Quest, skills []types.Skill) error {
+	for _, quest := range quests {
+		existing, err := store.GetQuestByTitle(quest.Title)
+		if err == nil {
+			quest.ID = existing.ID
+			if err = store.UpdateQuest(&quest); err != nil {
+				return err
+			}
+			continue
+		}
+		if !errors.Is(err, ErrNotFound) {
+			return err
+		}
+		if _, err = store.CreateQuest(&quest); err != nil {
+			return err
+		}
+	}
+
+	for _, skill := range skills {
+		existing, err := store.GetSkillByName(skill.Name)
+		if err == nil {
+			// keep levels and prerequisites in step with the file
+			skill.ID = existing.ID
+			if err = store.UpdateSkill(&skill); err != nil {
+				return err
+			}
+			continue
+		}
+		if !errors.Is(err, ErrNotFound) {
+			return err
+		}
+		if _, err = store.CreateSkill(&skill); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
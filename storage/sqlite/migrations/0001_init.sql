@@ -0,0 +1,43 @@
+create table if not exists players (
+    id integer primary key autoincrement,
+    name text null,
+    gender boolean null,
+    joined_at timestamp not null default current_timestamp
+);
+
+create table if not exists quests (
+    id integer primary key autoincrement,
+    description text null,
+    title text null,
+    priority smallint null
+);
+create index if not exists quests_priority_idx on quests (priority);
+create index if not exists quests_title_idx on quests (title);
+
+create table if not exists skills (
+    id integer primary key autoincrement,
+    name text null,
+    description text null,
+    level integer null
+);
+create index if not exists skills_level_idx on skills (level);
+create index if not exists skills_name_idx on skills (name);
+
+create table if not exists player_quests (
+    id integer primary key autoincrement,
+    start_at timestamp not null,
+    player integer null references players (id) on update cascade on delete cascade,
+    quest integer null references quests (id) on update cascade on delete cascade,
+    status integer not null default 0,
+    priority integer not null,
+    constraint player_quests_finish_check check (status >= -1 and status <= 2)
+);
+create index if not exists player_quests_player_idx on player_quests (player, start_at desc);
+
+create table if not exists player_skills (
+    id integer primary key autoincrement,
+    recived_at timestamp not null default current_timestamp,
+    skill integer not null references skills (id) on update cascade on delete cascade,
+    player integer not null references players (id) on update cascade on delete cascade,
+    constraint player_skills_skill_key unique (skill)
+);
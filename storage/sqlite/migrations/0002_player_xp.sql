@@ -0,0 +1 @@
+alter table players add column xp integer not null default 0;
@@ -0,0 +1,8 @@
+alter table players add column strength integer not null default 10;
+alter table players add column agility integer not null default 10;
+alter table players add column sense integer not null default 10;
+alter table players add column vitality integer not null default 10;
+alter table players add column intelligence integer not null default 10;
+alter table players add column stat_points integer not null default 0 check (stat_points >= 0);
+alter table players add column max_level integer not null default 1;
+alter table players add column title text null;
@@ -0,0 +1,15 @@
+-- skills are owned per player, every player can earn the whole catalogue.
+-- SQLite can't drop a constraint, so the table is rebuilt without it.
+create table player_skills_new (
+    id integer primary key autoincrement,
+    recived_at timestamp not null default current_timestamp,
+    skill integer not null references skills (id) on update cascade on delete cascade,
+    player integer not null references players (id) on update cascade on delete cascade,
+    constraint player_skills_player_skill_key unique (player, skill)
+);
+
+insert into player_skills_new (id, recived_at, skill, player)
+    select id, recived_at, skill, player from player_skills;
+
+drop table player_skills;
+alter table player_skills_new rename to player_skills;
@@ -0,0 +1,3 @@
+alter table skills add column max_level integer not null default 10;
+alter table player_skills add column level integer not null default 1;
+alter table player_skills add column xp integer not null default 0;
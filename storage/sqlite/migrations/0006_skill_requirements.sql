@@ -0,0 +1 @@
+alter table skills add column requires text null;
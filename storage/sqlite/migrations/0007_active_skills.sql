@@ -0,0 +1,15 @@
+alter table skills add column type text not null default 'passive';
+alter table skills add column cooldown integer not null default 0;
+alter table skills add column mana_cost integer not null default 0;
+
+alter table players add column mana integer not null default 0;
+alter table players add column mana_updated_at timestamp null;
+
+create table if not exists skill_usages (
+    id integer primary key autoincrement,
+    player integer not null references players (id) on update cascade on delete cascade,
+    skill integer not null references skills (id) on update cascade on delete cascade,
+    used_at timestamp not null,
+    mana_cost integer not null default 0
+);
+create index if not exists skill_usages_player_skill_idx on skill_usages (player, skill, used_at desc);
@@ -0,0 +1,5 @@
+alter table skills add column effects text null;
+
+alter table player_quests add column deadline timestamp null;
+update player_quests set deadline = strftime('%Y-%m-%d %H:%M:%f+00:00', start_at, '+24 hours') where deadline is null;
+create index if not exists player_quests_deadline_idx on player_quests (status, deadline);
@@ -0,0 +1,3 @@
+alter table quests add column objectives text null;
+
+alter table player_quests add column progress text null;
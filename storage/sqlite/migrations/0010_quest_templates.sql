@@ -0,0 +1,2 @@
+alter table player_quests add column description text null;
+alter table player_quests add column objectives text null;
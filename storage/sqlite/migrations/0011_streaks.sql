@@ -0,0 +1 @@
+alter table players add column longest_streak integer not null default 0;
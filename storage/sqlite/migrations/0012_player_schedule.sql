@@ -0,0 +1,2 @@
+alter table players add column time_zone text not null default 'UTC';
+alter table players add column reset_hour integer not null default 0 check (reset_hour >= 0 and reset_hour <= 23);
@@ -0,0 +1,2 @@
+alter table quests add column cadence text not null default 'daily';
+alter table player_quests add column cadence text not null default 'daily';
@@ -0,0 +1,3 @@
+alter table quests add column owner integer null references players (id) on update cascade on delete cascade;
+alter table quests add column archived boolean not null default false;
+create index if not exists quests_owner_idx on quests (owner);
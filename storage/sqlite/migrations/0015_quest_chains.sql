@@ -0,0 +1,11 @@
+alter table quests add column chain text null;
+alter table quests add column chain_step integer not null default 0;
+
+create table if not exists player_chains (
+    id integer primary key autoincrement,
+    player integer not null references players (id) on update cascade on delete cascade,
+    chain text not null,
+    step integer not null default 0,
+    updated_at timestamp not null,
+    unique (player, chain)
+);
@@ -0,0 +1 @@
+alter table quests add column trigger text null;
@@ -0,0 +1 @@
+alter table player_quests add column rerolled_from integer null references player_quests (id) on update cascade on delete set null;
@@ -0,0 +1,2 @@
+alter table quests add column category text null;
+alter table quests add column weight integer not null default 10;
@@ -0,0 +1,3 @@
+alter table players add column schedule_from timestamp null;
+alter table players add column previous_time_zone text null;
+alter table players add column previous_reset_hour integer null check (previous_reset_hour >= 0 and previous_reset_hour <= 23);
@@ -0,0 +1,90 @@
+package sqlite
+
+import (
+	"database/sql"
+	"embed"
+	"errors"
+	"io/fs"
+	"net/url"
+
+	"github.com/MultiX0/solo_leveling_system/storage"
+	"github.com/MultiX0/solo_leveling_system/storage/sqlstore"
+	"modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
+)
+
+//go:embed migrations/*.sql
+var migrations embed.FS
+
+var Dialect = sqlstore.Dialect{
+	Rebind:   func(query string) string { return query },
+	MapError: mapError,
+}
+
+// Open opens (or creates) the database file at path and applies any
+// pending migrations.
+func Open(path string) (*sqlstore.Store, error) {
+	db, err := Connect(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err = Migrate(db); err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return sqlstore.New(db, Dialect), nil
+}
+
+// Connect opens the database file with foreign keys enforced and times
+// stored in a format that sorts correctly as text.
+func Connect(path string) (*sql.DB, error) {
+	params := url.Values{}
+	params.Add("_pragma", "foreign_keys(1)")
+	params.Add("_pragma", "busy_timeout(5000)")
+	params.Add("_pragma", "journal_mode(WAL)")
+	params.Set("_time_format", "sqlite")
+
+	db, err := sql.Open("sqlite", "file:"+path+"?"+params.Encode())
+	if err != nil {
+		return nil, err
+	}
+
+	// SQLite allows a single writer, sharing one connection avoids
+	// "database is locked" errors between concurrent requests.
+	db.SetMaxOpenConns(1)
+
+	if err = db.Ping(); err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return db, nil
+}
+
+// Migrate brings the schema up to date and returns the applied version.
+func Migrate(db *sql.DB) (int, error) {
+	scripts, err := fs.Sub(migrations, "migrations")
+	if err != nil {
+		return 0, err
+	}
+
+	return sqlstore.Migrate(db, Dialect, scripts)
+}
+
+func mapError(err error) error {
+	var sqliteErr *sqlite.Error
+	if !errors.As(err, &sqliteErr) {
+		return err
+	}
+
+	switch sqliteErr.Code() {
+	case sqlite3.SQLITE_CONSTRAINT_UNIQUE, sqlite3.SQLITE_CONSTRAINT_PRIMARYKEY:
+		return storage.ErrConflict
+	case sqlite3.SQLITE_CONSTRAINT_FOREIGNKEY, sqlite3.SQLITE_CONSTRAINT_CHECK, sqlite3.SQLITE_CONSTRAINT_NOTNULL:
+		return storage.ErrConstraint
+	}
+
+	return err
+}
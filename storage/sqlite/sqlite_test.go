@@ -0,0 +1,138 @@
+package sqlite
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/MultiX0/solo_leveling_system/storage"
+	"github.com/MultiX0/solo_leveling_system/storage/sqlstore"
+	"github.com/MultiX0/solo_leveling_system/types"
+)
+
+var errAbort = errors.New("abort")
+
+func TestAtomicRollsBack(t *testing.T) {
+	store := openStore(t)
+	player, err := store.CreatePlayer(&types.Player{Name: "Jinwoo"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	quest, err := store.CreateQuest(&types.Quest{Title: "Push-ups", Priority: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = store.Atomic(func(tx storage.Storage) error {
+		if _, err := tx.AddPlayerXP(player.ID, 100); err != nil {
+			return err
+		}
+		if _, err := tx.CreatePlayerQuest(&types.PlayerQuest{PlayerID: player.ID, QuestID: quest.ID, Priority: 1}); err != nil {
+			return err
+		}
+		// nested calls join the same transaction
+		return tx.Atomic(func(tx storage.Storage) error {
+			if _, err := tx.AddPlayerXP(player.ID, 50); err != nil {
+				return err
+			}
+			return errAbort
+		})
+	})
+	if !errors.Is(err, errAbort) {
+		t.Fatalf("Atomic returned %v, want %v", err, errAbort)
+	}
+
+	got, err := store.GetPlayer(player.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.XP != 0 {
+		t.Errorf("xp after rollback = %d, want 0", got.XP)
+	}
+
+	given, err := store.ListPlayerQuests(storage.PlayerQuestFilter{PlayerID: player.ID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(given) != 0 {
+		t.Errorf("%d player quests after rollback, want 0", len(given))
+	}
+}
+
+func TestAtomicCommits(t *testing.T) {
+	store := openStore(t)
+	player, err := store.CreatePlayer(&types.Player{Name: "Jinwoo"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = store.Atomic(func(tx storage.Storage) error {
+		_, err := tx.AddPlayerXP(player.ID, 100)
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := store.GetPlayer(player.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.XP != 100 {
+		t.Errorf("xp after commit = %d, want 100", got.XP)
+	}
+}
+
+func openStore(t *testing.T) *sqlstore.Store {
+	t.Helper()
+
+	store, err := Open(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { store.DB().Close() })
+
+	return store
+}
+
+func TestUpdatePlayerSkillProgressNotFound(t *testing.T) {
+	store := openStore(t)
+
+	if err := store.UpdatePlayerSkillProgress(42, 2, 10); !errors.Is(err, storage.ErrNotFound) {
+		t.Errorf("updating a missing player skill: %v, want %v", err, storage.ErrNotFound)
+	}
+}
+
+func TestSpendPlayerManaOnlyFromWhatWasRead(t *testing.T) {
+	store := openStore(t)
+	player, err := store.CreatePlayer(&types.Player{Name: "Jinwoo"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	first := time.Date(2026, 10, 12, 10, 0, 0, 123456000, time.UTC)
+	if spent, err := store.SpendPlayerMana(player.ID, 0, nil, 75, first); err != nil || !spent {
+		t.Fatalf("spending mana the player never spent: %v, %v", spent, err)
+	}
+	// another use read the player before the first one spent
+	if spent, err := store.SpendPlayerMana(player.ID, 0, nil, 50, first); err != nil || spent {
+		t.Fatalf("spending from a stale read: %v, %v", spent, err)
+	}
+
+	read, err := store.GetPlayer(player.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if read.Mana != 75 || read.ManaUpdatedAt == nil || !read.ManaUpdatedAt.Equal(first) {
+		t.Fatalf("mana %d as of %v, want 75 as of %v", read.Mana, read.ManaUpdatedAt, first)
+	}
+
+	second := first.Add(time.Minute)
+	if spent, err := store.SpendPlayerMana(player.ID, read.Mana, read.ManaUpdatedAt, 60, second); err != nil || !spent {
+		t.Fatalf("spending from what was read: %v, %v", spent, err)
+	}
+	if spent, err := store.SpendPlayerMana(player.ID, read.Mana, read.ManaUpdatedAt, 40, second); err != nil || spent {
+		t.Fatalf("spending from what was read before: %v, %v", spent, err)
+	}
+}
@@ -0,0 +1,123 @@
+package sqlstore
+
+import (
+	"database/sql"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const createMigrationsTable = `create table if not exists schema_migrations (
+	version integer primary key,
+	applied_at timestamp not null default current_timestamp
+)`
+
+type migration struct {
+	version int
+	name    string
+}
+
+// Migrate applies every NNNN_name.sql file in fsys that is newer than the
+// recorded schema version, each in its own transaction, and returns the
+// version the database ends up at.
+func Migrate(db *sql.DB, dialect Dialect, fsys fs.FS) (int, error) {
+	if _, err := db.Exec(createMigrationsTable); err != nil {
+		return 0, err
+	}
+
+	current, err := SchemaVersion(db)
+	if err != nil {
+		return 0, err
+	}
+
+	migrations, err := readMigrations(fsys)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, m := range migrations {
+		if m.version <= current {
+			continue
+		}
+
+		script, err := fs.ReadFile(fsys, m.name)
+		if err != nil {
+			return current, err
+		}
+
+		if err = apply(db, dialect, m.version, string(script)); err != nil {
+			return current, fmt.Errorf("migration %s: %w", m.name, err)
+		}
+
+		current = m.version
+	}
+
+	return current, nil
+}
+
+// SchemaVersion returns the highest applied migration, 0 on a fresh database.
+func SchemaVersion(db *sql.DB) (int, error) {
+	var version sql.NullInt64
+	err := db.QueryRow("select max(version) from schema_migrations").Scan(&version)
+	if err != nil {
+		return 0, err
+	}
+
+	return int(version.Int64), nil
+}
+
+func apply(db *sql.DB, dialect Dialect, version int, script string) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err = tx.Exec(script); err != nil {
+		return err
+	}
+
+	if _, err = tx.Exec(dialect.Rebind("insert into schema_migrations (version) values (?)"), version); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func readMigrations(fsys fs.FS) ([]migration, error) {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, err
+	}
+
+	var migrations []migration
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || path.Ext(name) != ".sql" {
+			continue
+		}
+
+		prefix, _, _ := strings.Cut(name, "_")
+		version, err := strconv.Atoi(prefix)
+		if err != nil {
+			return nil, fmt.Errorf("migration %s: file name must start with a version number", name)
+		}
+
+		migrations = append(migrations, migration{version: version, name: name})
+	}
+
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].version < migrations[j].version
+	})
+
+	for i := 1; i < len(migrations); i++ {
+		if migrations[i].version == migrations[i-1].version {
+			return nil, fmt.Errorf("migrations %s and %s share a version", migrations[i-1].name, migrations[i].name)
+		}
+	}
+
+	return migrations, nil
+}
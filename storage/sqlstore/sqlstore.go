@@ -0,0 +1,901 @@
+package sqlstore
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/MultiX0/solo_leveling_system/storage"
+	"github.com/MultiX0/solo_leveling_system/types"
+)
+
+// Dialect holds what differs between the SQL databases Store runs on.
+type Dialect struct {
+	// Rebind turns the ? placeholders used in this package into the
+	// driver's own syntax.
+	Rebind func(query string) string
+	// MapError translates driver errors into the storage errors.
+	MapError func(err error) error
+}
+
+// Store implements storage.Storage on top of database/sql. The driver
+// specific packages (postgres, sqlite) open the connection, run their
+// migrations and hand it over together with their Dialect.
+type Store struct {
+	db      *sql.DB
+	q       querier
+	inTx    bool
+	dialect Dialect
+}
+
+// querier is what *sql.DB and *sql.Tx have in common.
+type querier interface {
+	Exec(query string, args ...any) (sql.Result, error)
+	Query(query string, args ...any) (*sql.Rows, error)
+	QueryRow(query string, args ...any) *sql.Row
+}
+
+func New(db *sql.DB, dialect Dialect) *Store {
+	return &Store{db: db, q: db, dialect: dialect}
+}
+
+func (s *Store) DB() *sql.DB {
+	return s.db
+}
+
+func (s *Store) Atomic(fn func(tx storage.Storage) error) error {
+	if s.inTx {
+		return fn(s)
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return s.mapError(err)
+	}
+	defer tx.Rollback()
+
+	if err = fn(&Store{db: s.db, q: tx, inTx: true, dialect: s.dialect}); err != nil {
+		return err
+	}
+
+	return s.mapError(tx.Commit())
+}
+
+func (s *Store) exec(query string, args ...any) (sql.Result, error) {
+	res, err := s.q.Exec(s.dialect.Rebind(query), args...)
+	return res, s.mapError(err)
+}
+
+func (s *Store) query(query string, args ...any) (*sql.Rows, error) {
+	rows, err := s.q.Query(s.dialect.Rebind(query), args...)
+	return rows, s.mapError(err)
+}
+
+func (s *Store) queryRow(query string, args ...any) *sql.Row {
+	return s.q.QueryRow(s.dialect.Rebind(query), args...)
+}
+
+func (s *Store) mapError(err error) error {
+	if err == nil {
+		return nil
+	}
+	if errors.Is(err, sql.ErrNoRows) {
+		return storage.ErrNotFound
+	}
+	return s.dialect.MapError(err)
+}
+
+// RebindDollar rewrites ? placeholders to the $1, $2, ... form postgres expects.
+func RebindDollar(query string) string {
+	var b strings.Builder
+	n := 0
+	for _, r := range query {
+		if r == '?' {
+			n++
+			b.WriteString("$")
+			b.WriteString(strconv.Itoa(n))
+			continue
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+const playerColumns = "id, name, gender, joined_at, xp, strength, agility, sense, vitality, intelligence, stat_points, max_level, title, mana, mana_updated_at, longest_streak, time_zone, reset_hour, schedule_from, previous_time_zone, previous_reset_hour"
+
+func scanPlayer(row scanner) (*types.Player, error) {
+	var player types.Player
+	var name, title, previousTimeZone sql.NullString
+	var gender sql.NullBool
+	var manaUpdatedAt, scheduleFrom sql.NullTime
+	var previousResetHour sql.NullInt64
+	err := row.Scan(&player.ID, &name, &gender, &player.JoinedAt, &player.XP,
+		&player.Strength, &player.Agility, &player.Sense, &player.Vitality, &player.Intelligence,
+		&player.StatPoints, &player.MaxLevel, &title, &player.Mana, &manaUpdatedAt, &player.LongestStreak, &player.TimeZone, &player.ResetHour,
+		&scheduleFrom, &previousTimeZone, &previousResetHour)
+	if err != nil {
+		return nil, err
+	}
+	player.Name = name.String
+	player.Gender = gender.Bool
+	player.Title = title.String
+	if manaUpdatedAt.Valid {
+		player.ManaUpdatedAt = &manaUpdatedAt.Time
+	}
+	if scheduleFrom.Valid {
+		player.ScheduleFrom = &scheduleFrom.Time
+	}
+	player.PreviousTimeZone = previousTimeZone.String
+	player.PreviousResetHour = int(previousResetHour.Int64)
+	return &player, nil
+}
+
+func (s *Store) CreatePlayer(player *types.Player) (*types.Player, error) {
+	row := s.queryRow("insert into players (name, gender, joined_at) values (?, ?, ?) returning "+playerColumns,
+		player.Name, player.Gender, time.Now().UTC())
+
+	newPlayer, err := scanPlayer(row)
+	return newPlayer, s.mapError(err)
+}
+
+func (s *Store) GetPlayer(id int) (*types.Player, error) {
+	player, err := scanPlayer(s.queryRow("select "+playerColumns+" from players where id = ?", id))
+	return player, s.mapError(err)
+}
+
+func (s *Store) ListPlayers() ([]*types.Player, error) {
+	rows, err := s.query("select " + playerColumns + " from players order by id")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var players []*types.Player
+	for rows.Next() {
+		player, err := scanPlayer(rows)
+		if err != nil {
+			return nil, err
+		}
+		players = append(players, player)
+	}
+
+	return players, s.mapError(rows.Err())
+}
+
+func (s *Store) AddPlayerXP(id int, delta int) (*types.Player, error) {
+	row := s.queryRow("update players set xp = case when xp + ? < 0 then 0 else xp + ? end where id = ? returning "+playerColumns,
+		delta, delta, id)
+
+	player, err := scanPlayer(row)
+	return player, s.mapError(err)
+}
+
+func (s *Store) AddPlayerStats(id int, stats types.Stats, statPoints int) (*types.Player, error) {
+	row := s.queryRow(`update players set
+		strength = strength + ?, agility = agility + ?, sense = sense + ?,
+		vitality = vitality + ?, intelligence = intelligence + ?,
+		stat_points = stat_points + ?
+		where id = ? returning `+playerColumns,
+		stats.Strength, stats.Agility, stats.Sense, stats.Vitality, stats.Intelligence, statPoints, id)
+
+	player, err := scanPlayer(row)
+	return player, s.mapError(err)
+}
+
+func (s *Store) LevelUpPlayer(id int, from, to int, statPoints int) (bool, error) {
+	res, err := s.exec("update players set max_level = ?, stat_points = stat_points + ? where id = ? and max_level = ?",
+		to, statPoints, id, from)
+	if err != nil {
+		return false, err
+	}
+
+	count, err := res.RowsAffected()
+	return count > 0, err
+}
+
+func (s *Store) SpendPlayerMana(id int, fromMana int, fromAt *time.Time, mana int, at time.Time) (bool, error) {
+	query := "update players set mana = ?, mana_updated_at = ? where id = ? and mana = ? and mana_updated_at is null"
+	args := []any{mana, at.UTC(), id, fromMana}
+	if fromAt != nil {
+		query = "update players set mana = ?, mana_updated_at = ? where id = ? and mana = ? and mana_updated_at = ?"
+		args = append(args, fromAt.UTC())
+	}
+
+	res, err := s.exec(query, args...)
+	if err != nil {
+		return false, err
+	}
+
+	count, err := res.RowsAffected()
+	return count > 0, err
+}
+
+func (s *Store) RecordPlayerStreak(id int, streak int) error {
+	res, err := s.exec("update players set longest_streak = case when longest_streak < ? then ? else longest_streak end where id = ?",
+		streak, streak, id)
+	if err != nil {
+		return err
+	}
+
+	if count, err := res.RowsAffected(); err == nil && count == 0 {
+		return storage.ErrNotFound
+	}
+
+	return nil
+}
+
+func (s *Store) UpdatePlayerTitle(id int, title string) error {
+	res, err := s.exec("update players set title = ? where id = ?", title, id)
+	if err != nil {
+		return err
+	}
+
+	if count, err := res.RowsAffected(); err == nil && count == 0 {
+		return storage.ErrNotFound
+	}
+
+	return nil
+}
+
+func (s *Store) UpdatePlayerSchedule(id int, schedule types.Schedule) error {
+	var from *time.Time
+	if schedule.ScheduleFrom != nil {
+		utc := schedule.ScheduleFrom.UTC()
+		from = &utc
+	}
+
+	res, err := s.exec("update players set time_zone = ?, reset_hour = ?, schedule_from = ?, previous_time_zone = ?, previous_reset_hour = ? where id = ?",
+		schedule.TimeZone, schedule.ResetHour, from, schedule.PreviousTimeZone, schedule.PreviousResetHour, id)
+	if err != nil {
+		return err
+	}
+
+	if count, err := res.RowsAffected(); err == nil && count == 0 {
+		return storage.ErrNotFound
+	}
+
+	return nil
+}
+
+const questColumns = "id, title, description, priority, objectives, cadence, owner, archived, chain, chain_step, trigger, category, weight"
+
+func scanQuest(row scanner) (*types.Quest, error) {
+	var quest types.Quest
+	var title, description, objectives, chain, trigger, category sql.NullString
+	var priority, owner sql.NullInt64
+	err := row.Scan(&quest.ID, &title, &description, &priority, &objectives, &quest.Cadence, &owner, &quest.Archived,
+		&chain, &quest.ChainStep, &trigger, &category, &quest.Weight)
+	if err != nil {
+		return nil, err
+	}
+	quest.Owner = int(owner.Int64)
+	quest.Chain = chain.String
+	quest.Category = category.String
+	quest.Title = title.String
+	quest.Description = description.String
+	quest.Priority = int(priority.Int64)
+	if objectives.Valid {
+		if err := json.Unmarshal([]byte(objectives.String), &quest.Objectives); err != nil {
+			return nil, err
+		}
+	}
+	if trigger.Valid {
+		if err := json.Unmarshal([]byte(trigger.String), &quest.Trigger); err != nil {
+			return nil, err
+		}
+	}
+	return &quest, nil
+}
+
+func (s *Store) CreateQuest(quest *types.Quest) (*types.Quest, error) {
+	objectives, err := jsonSlice(quest.Objectives)
+	if err != nil {
+		return nil, err
+	}
+
+	trigger, err := jsonValue(quest.Trigger)
+	if err != nil {
+		return nil, err
+	}
+
+	var owner any
+	if quest.Owner != 0 {
+		owner = quest.Owner
+	}
+
+	row := s.queryRow(`insert into quests (title, description, priority, objectives, cadence, owner, chain, chain_step, trigger,
+		category, weight) values (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?) returning `+questColumns,
+		quest.Title, quest.Description, quest.Priority, objectives, questCadence(quest.Cadence), owner,
+		questChain(quest), quest.ChainStep, trigger, questCategory(quest), questWeight(quest))
+
+	newQuest, err := scanQuest(row)
+	return newQuest, s.mapError(err)
+}
+
+func (s *Store) UpdateQuest(quest *types.Quest) error {
+	objectives, err := jsonSlice(quest.Objectives)
+	if err != nil {
+		return err
+	}
+
+	trigger, err := jsonValue(quest.Trigger)
+	if err != nil {
+		return err
+	}
+
+	res, err := s.exec(`update quests set title = ?, description = ?, priority = ?, objectives = ?, cadence = ?,
+		chain = ?, chain_step = ?, trigger = ?, category = ?, weight = ? where id = ?`,
+		quest.Title, quest.Description, quest.Priority, objectives, questCadence(quest.Cadence),
+		questChain(quest), quest.ChainStep, trigger, questCategory(quest), questWeight(quest), quest.ID)
+	if err != nil {
+		return err
+	}
+
+	if count, err := res.RowsAffected(); err == nil && count == 0 {
+		return storage.ErrNotFound
+	}
+
+	return nil
+}
+
+func (s *Store) ArchiveQuest(id int) error {
+	res, err := s.exec("update quests set archived = true where id = ?", id)
+	if err != nil {
+		return err
+	}
+
+	if count, err := res.RowsAffected(); err == nil && count == 0 {
+		return storage.ErrNotFound
+	}
+
+	return nil
+}
+
+func (s *Store) GetQuest(id int) (*types.Quest, error) {
+	quest, err := scanQuest(s.queryRow("select "+questColumns+" from quests where id = ?", id))
+	return quest, s.mapError(err)
+}
+
+func (s *Store) GetQuestByTitle(title string) (*types.Quest, error) {
+	quest, err := scanQuest(s.queryRow("select "+questColumns+" from quests where title = ? and owner is null order by id limit 1", title))
+	return quest, s.mapError(err)
+}
+
+func kindClause(kind storage.QuestKind) string {
+	switch kind {
+	case storage.MainQuests:
+		return " and priority = 1 and cadence = 'daily'"
+	case storage.SideQuests:
+		return " and priority > 1 and cadence = 'daily'"
+	case storage.PenaltyQuests:
+		return " and priority = 0 and cadence = 'daily'"
+	case storage.WeeklyQuests:
+		return " and cadence = 'weekly'"
+	case storage.MonthlyQuests:
+		return " and cadence = 'monthly'"
+	case storage.EventQuests:
+		return " and cadence = 'event'"
+	}
+	return ""
+}
+
+func (s *Store) ListQuests(kind storage.QuestKind) ([]types.Quest, error) {
+	return s.listQuests("select " + questColumns + " from quests where owner is null and not archived" + kindClause(kind) + " order by id")
+}
+
+func (s *Store) ListCustomQuests(ownerID int, kind storage.QuestKind) ([]types.Quest, error) {
+	return s.listQuests("select "+questColumns+" from quests where owner = ? and not archived"+kindClause(kind)+" order by id", ownerID)
+}
+
+func (s *Store) listQuests(query string, args ...any) ([]types.Quest, error) {
+	rows, err := s.query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var quests []types.Quest
+	for rows.Next() {
+		quest, err := scanQuest(rows)
+		if err != nil {
+			return nil, err
+		}
+		quests = append(quests, *quest)
+	}
+
+	return quests, s.mapError(rows.Err())
+}
+
+const skillColumns = "id, name, description, level, max_level, requires, type, cooldown, mana_cost, effects"
+
+func scanSkill(row scanner) (*types.Skill, error) {
+	var skill types.Skill
+	var name, description, requires, effects sql.NullString
+	var level sql.NullInt64
+	err := row.Scan(&skill.ID, &name, &description, &level, &skill.MaxLevel, &requires,
+		&skill.Type, &skill.Cooldown, &skill.ManaCost, &effects)
+	if err != nil {
+		return nil, err
+	}
+	skill.Name = name.String
+	skill.Description = description.String
+	skill.Level = int(level.Int64)
+	if requires.Valid {
+		if err := json.Unmarshal([]byte(requires.String), &skill.Requires); err != nil {
+			return nil, err
+		}
+	}
+	if effects.Valid {
+		if err := json.Unmarshal([]byte(effects.String), &skill.Effects); err != nil {
+			return nil, err
+		}
+	}
+	return &skill, nil
+}
+
+func questCadence(cadence string) string {
+	if cadence == "" {
+		return types.CadenceDaily
+	}
+	return cadence
+}
+
+// questChain stores quests outside of any chain with a NULL chain.
+func questChain(quest *types.Quest) any {
+	if quest.Chain == "" {
+		return nil
+	}
+	return quest.Chain
+}
+
+// questCategory stores quests outside of any category with a NULL
+// category.
+func questCategory(quest *types.Quest) any {
+	if quest.Category == "" {
+		return nil
+	}
+	return quest.Category
+}
+
+func questWeight(quest *types.Quest) int {
+	if quest.Weight <= 0 {
+		return types.DefaultQuestWeight
+	}
+	return quest.Weight
+}
+
+func skillType(skill *types.Skill) string {
+	if skill.Type == "" {
+		return types.SkillPassive
+	}
+	return skill.Type
+}
+
+// jsonValue encodes v for a json/jsonb column, nil pointers become NULL.
+func jsonValue[T any](v *T) (any, error) {
+	if v == nil {
+		return nil, nil
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	return string(data), nil
+}
+
+// jsonSlice is jsonValue for slices, empty ones become NULL.
+func jsonSlice[T any](v []T) (any, error) {
+	if len(v) == 0 {
+		return nil, nil
+	}
+	return jsonValue(&v)
+}
+
+func (s *Store) CreateSkill(skill *types.Skill) (*types.Skill, error) {
+	requires, err := jsonValue(skill.Requires)
+	if err != nil {
+		return nil, err
+	}
+
+	effects, err := jsonValue(skill.Effects)
+	if err != nil {
+		return nil, err
+	}
+
+	row := s.queryRow(`insert into skills (name, description, level, max_level, requires, type, cooldown, mana_cost, effects)
+		values (?, ?, ?, ?, ?, ?, ?, ?, ?) returning `+skillColumns,
+		skill.Name, skill.Description, skill.Level, skill.MaxLevel, requires, skillType(skill), skill.Cooldown, skill.ManaCost, effects)
+
+	newSkill, err := scanSkill(row)
+	return newSkill, s.mapError(err)
+}
+
+func (s *Store) GetSkill(id int) (*types.Skill, error) {
+	skill, err := scanSkill(s.queryRow("select "+skillColumns+" from skills where id = ?", id))
+	return skill, s.mapError(err)
+}
+
+func (s *Store) GetSkillByName(name string) (*types.Skill, error) {
+	skill, err := scanSkill(s.queryRow("select "+skillColumns+" from skills where name = ? order by id limit 1", name))
+	return skill, s.mapError(err)
+}
+
+func (s *Store) ListSkills() ([]*types.Skill, error) {
+	rows, err := s.query("select " + skillColumns + " from skills order by id")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var skills []*types.Skill
+	for rows.Next() {
+		skill, err := scanSkill(rows)
+		if err != nil {
+			return nil, err
+		}
+		skills = append(skills, skill)
+	}
+
+	return skills, s.mapError(rows.Err())
+}
+
+func (s *Store) UpdateSkill(skill *types.Skill) error {
+	requires, err := jsonValue(skill.Requires)
+	if err != nil {
+		return err
+	}
+
+	effects, err := jsonValue(skill.Effects)
+	if err != nil {
+		return err
+	}
+
+	res, err := s.exec(`update skills set name = ?, description = ?, level = ?, max_level = ?, requires = ?,
+		type = ?, cooldown = ?, mana_cost = ?, effects = ? where id = ?`,
+		skill.Name, skill.Description, skill.Level, skill.MaxLevel, requires,
+		skillType(skill), skill.Cooldown, skill.ManaCost, effects, skill.ID)
+	if err != nil {
+		return err
+	}
+
+	if count, err := res.RowsAffected(); err == nil && count == 0 {
+		return storage.ErrNotFound
+	}
+
+	return nil
+}
+
+const playerQuestColumns = "id, start_at, player, quest, status, priority, deadline, progress, description, objectives, cadence, rerolled_from"
+
+func scanPlayerQuest(row scanner) (*types.PlayerQuest, error) {
+	var pq types.PlayerQuest
+	var player, quest, rerolledFrom sql.NullInt64
+	var deadline sql.NullTime
+	var progress, description, objectives sql.NullString
+	err := row.Scan(&pq.ID, &pq.StartAt, &player, &quest, &pq.Status, &pq.Priority, &deadline,
+		&progress, &description, &objectives, &pq.Cadence, &rerolledFrom)
+	if err != nil {
+		return nil, err
+	}
+	pq.Description = description.String
+	if objectives.Valid {
+		if err := json.Unmarshal([]byte(objectives.String), &pq.Objectives); err != nil {
+			return nil, err
+		}
+	}
+	if progress.Valid {
+		if err := json.Unmarshal([]byte(progress.String), &pq.Progress); err != nil {
+			return nil, err
+		}
+	}
+	pq.PlayerID = int(player.Int64)
+	pq.QuestID = int(quest.Int64)
+	pq.RerolledFrom = int(rerolledFrom.Int64)
+	pq.Deadline = deadline.Time
+	if !deadline.Valid {
+		pq.Deadline = pq.StartAt.Add(types.DefaultQuestDeadline)
+	}
+	return &pq, nil
+}
+
+func (s *Store) CreatePlayerQuest(pq *types.PlayerQuest) (*types.PlayerQuest, error) {
+	startAt := pq.StartAt
+	if startAt.IsZero() {
+		startAt = time.Now()
+	}
+
+	deadline := pq.Deadline
+	if deadline.IsZero() {
+		deadline = startAt.Add(types.DefaultQuestDeadline)
+	}
+
+	progress, err := jsonSlice(pq.Progress)
+	if err != nil {
+		return nil, err
+	}
+
+	objectives, err := jsonSlice(pq.Objectives)
+	if err != nil {
+		return nil, err
+	}
+
+	var description any
+	if pq.Description != "" {
+		description = pq.Description
+	}
+
+	var rerolledFrom any
+	if pq.RerolledFrom != 0 {
+		rerolledFrom = pq.RerolledFrom
+	}
+
+	row := s.queryRow(`insert into player_quests (start_at, player, quest, status, priority, deadline, progress, description, objectives,
+		cadence, rerolled_from) values (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?) returning `+playerQuestColumns,
+		startAt.UTC(), pq.PlayerID, pq.QuestID, pq.Status, pq.Priority, deadline.UTC(), progress, description, objectives,
+		questCadence(pq.Cadence), rerolledFrom)
+
+	newPQ, err := scanPlayerQuest(row)
+	return newPQ, s.mapError(err)
+}
+
+func whereClause(filter storage.PlayerQuestFilter) (string, []any) {
+	where := " where true"
+	var args []any
+
+	if filter.ID != 0 {
+		where += " and id = ?"
+		args = append(args, filter.ID)
+	}
+	if filter.PlayerID != 0 {
+		where += " and player = ?"
+		args = append(args, filter.PlayerID)
+	}
+	if filter.QuestID != 0 {
+		where += " and quest = ?"
+		args = append(args, filter.QuestID)
+	}
+	if len(filter.Status) > 0 {
+		where += " and status in (?" + strings.Repeat(", ?", len(filter.Status)-1) + ")"
+		for _, status := range filter.Status {
+			args = append(args, status)
+		}
+	}
+	if len(filter.Priority) > 0 {
+		where += " and priority in (?" + strings.Repeat(", ?", len(filter.Priority)-1) + ")"
+		for _, priority := range filter.Priority {
+			args = append(args, priority)
+		}
+	}
+
+	where += kindClause(filter.Kind)
+
+	if !filter.StartedBefore.IsZero() {
+		where += " and start_at < ?"
+		args = append(args, filter.StartedBefore.UTC())
+	}
+	if !filter.StartedAfter.IsZero() {
+		where += " and start_at >= ?"
+		args = append(args, filter.StartedAfter.UTC())
+	}
+	if !filter.DeadlineBefore.IsZero() {
+		where += " and deadline < ?"
+		args = append(args, filter.DeadlineBefore.UTC())
+	}
+	if filter.After != nil {
+		where += " and (start_at < ? or (start_at = ? and id < ?))"
+		args = append(args, filter.After.StartAt.UTC(), filter.After.StartAt.UTC(), filter.After.ID)
+	}
+
+	return where, args
+}
+
+func (s *Store) ListPlayerQuests(filter storage.PlayerQuestFilter) ([]*types.PlayerQuest, error) {
+	where, args := whereClause(filter)
+	query := "select " + playerQuestColumns + " from player_quests" + where + " order by start_at desc, id desc"
+	if filter.Limit > 0 {
+		query += " limit ?"
+		args = append(args, filter.Limit)
+	}
+
+	rows, err := s.query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var quests []*types.PlayerQuest
+	for rows.Next() {
+		pq, err := scanPlayerQuest(rows)
+		if err != nil {
+			return nil, err
+		}
+		quests = append(quests, pq)
+	}
+
+	return quests, s.mapError(rows.Err())
+}
+
+func (s *Store) UpdatePlayerQuestStatus(filter storage.PlayerQuestFilter, status int) (int, error) {
+	where, args := whereClause(filter)
+
+	res, err := s.exec("update player_quests set status = ?"+where, append([]any{status}, args...)...)
+	if err != nil {
+		return 0, err
+	}
+
+	count, err := res.RowsAffected()
+	return int(count), err
+}
+
+func (s *Store) UpdatePlayerQuestProgress(id int, progress []float64) error {
+	value, err := jsonSlice(progress)
+	if err != nil {
+		return err
+	}
+
+	res, err := s.exec("update player_quests set progress = ? where id = ?", value, id)
+	if err != nil {
+		return err
+	}
+
+	if count, err := res.RowsAffected(); err == nil && count == 0 {
+		return storage.ErrNotFound
+	}
+
+	return nil
+}
+
+func (s *Store) CountPlayerQuests(filter storage.PlayerQuestFilter) (map[int]int, error) {
+	filter.After = nil
+	where, args := whereClause(filter)
+
+	rows, err := s.query("select status, count(*) from player_quests"+where+" group by status", args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	counts := map[int]int{}
+	for rows.Next() {
+		var status, count int
+		if err := rows.Scan(&status, &count); err != nil {
+			return nil, err
+		}
+		counts[status] = count
+	}
+
+	return counts, s.mapError(rows.Err())
+}
+
+const playerSkillColumns = "id, recived_at, skill, player, level, xp"
+
+func scanPlayerSkill(row scanner) (*types.PlayerSkills, error) {
+	var ps types.PlayerSkills
+	if err := row.Scan(&ps.ID, &ps.RecivedAt, &ps.SkillID, &ps.PlayerID, &ps.Level, &ps.XP); err != nil {
+		return nil, err
+	}
+	return &ps, nil
+}
+
+func (s *Store) CreatePlayerSkill(ps *types.PlayerSkills) (*types.PlayerSkills, error) {
+	row := s.queryRow("insert into player_skills (recived_at, skill, player, level, xp) values (?, ?, ?, ?, ?) returning "+playerSkillColumns,
+		time.Now().UTC(), ps.SkillID, ps.PlayerID, max(ps.Level, 1), ps.XP)
+
+	newPS, err := scanPlayerSkill(row)
+	return newPS, s.mapError(err)
+}
+
+func (s *Store) GetPlayerSkill(playerID, skillID int) (*types.PlayerSkills, error) {
+	ps, err := scanPlayerSkill(s.queryRow("select "+playerSkillColumns+" from player_skills where player = ? and skill = ?", playerID, skillID))
+	return ps, s.mapError(err)
+}
+
+func (s *Store) UpdatePlayerSkillProgress(id int, level, xp int) error {
+	res, err := s.exec("update player_skills set level = ?, xp = ? where id = ?", level, xp, id)
+	if err != nil {
+		return err
+	}
+
+	if count, err := res.RowsAffected(); err == nil && count == 0 {
+		return storage.ErrNotFound
+	}
+
+	return nil
+}
+
+func (s *Store) ListPlayerSkills(playerID int) ([]*types.PlayerSkills, error) {
+	rows, err := s.query("select "+playerSkillColumns+" from player_skills where player = ? order by id", playerID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var skills []*types.PlayerSkills
+	for rows.Next() {
+		ps, err := scanPlayerSkill(rows)
+		if err != nil {
+			return nil, err
+		}
+		skills = append(skills, ps)
+	}
+
+	return skills, s.mapError(rows.Err())
+}
+
+const skillUsageColumns = "id, player, skill, used_at, mana_cost"
+
+func scanSkillUsage(row scanner) (*types.SkillUsage, error) {
+	var usage types.SkillUsage
+	if err := row.Scan(&usage.ID, &usage.PlayerID, &usage.SkillID, &usage.UsedAt, &usage.ManaCost); err != nil {
+		return nil, err
+	}
+	return &usage, nil
+}
+
+func (s *Store) CreateSkillUsage(usage *types.SkillUsage) (*types.SkillUsage, error) {
+	usedAt := usage.UsedAt
+	if usedAt.IsZero() {
+		usedAt = time.Now()
+	}
+
+	row := s.queryRow("insert into skill_usages (player, skill, used_at, mana_cost) values (?, ?, ?, ?) returning "+skillUsageColumns,
+		usage.PlayerID, usage.SkillID, usedAt.UTC(), usage.ManaCost)
+
+	newUsage, err := scanSkillUsage(row)
+	return newUsage, s.mapError(err)
+}
+
+func (s *Store) LastSkillUsage(playerID, skillID int) (*types.SkillUsage, error) {
+	usage, err := scanSkillUsage(s.queryRow("select "+skillUsageColumns+" from skill_usages where player = ? and skill = ? order by used_at desc, id desc limit 1",
+		playerID, skillID))
+	return usage, s.mapError(err)
+}
+
+const playerChainColumns = "id, player, chain, step, updated_at"
+
+func (s *Store) ListPlayerChains(playerID int) ([]*types.PlayerChain, error) {
+	rows, err := s.query("select "+playerChainColumns+" from player_chains where player = ? order by chain", playerID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var chains []*types.PlayerChain
+	for rows.Next() {
+		var pc types.PlayerChain
+		if err := rows.Scan(&pc.ID, &pc.PlayerID, &pc.Chain, &pc.Step, &pc.UpdatedAt); err != nil {
+			return nil, err
+		}
+		chains = append(chains, &pc)
+	}
+
+	return chains, s.mapError(rows.Err())
+}
+
+func (s *Store) AdvancePlayerChain(playerID int, chain string, step int) error {
+	_, err := s.exec(`insert into player_chains (player, chain, step, updated_at) values (?, ?, ?, ?)
+		on conflict (player, chain) do update set step = excluded.step, updated_at = excluded.updated_at
+		where player_chains.step < excluded.step`,
+		playerID, chain, step, time.Now().UTC())
+	return err
+}
+
+func (s *Store) InitSetting(name, value string) (string, error) {
+	if _, err := s.exec("insert into settings (name, value) values (?, ?) on conflict (name) do nothing", name, value); err != nil {
+		return "", err
+	}
+
+	var stored string
+	if err := s.queryRow("select value from settings where name = ?", name).Scan(&stored); err != nil {
+		return "", s.mapError(err)
+	}
+
+	return stored, nil
+}
@@ -0,0 +1,178 @@
+package storage
+
+import (
+	"errors"
+	"time"
+
+	"github.com/MultiX0/solo_leveling_system/types"
+)
+
+var (
+	ErrNotFound = errors.New("record not found")
+	ErrConflict = errors.New("record already exists")
+	// ErrConstraint covers the check and foreign key constraints of the
+	// schema in the README.
+	ErrConstraint = errors.New("constraint violation")
+)
+
+// Storage is everything the quest and skill system needs to persist.
+// Backends implement it once and get injected into the handlers and jobs.
+type Storage interface {
+	Players
+	Quests
+	Skills
+	PlayerQuests
+	PlayerSkills
+	SkillUsages
+	PlayerChains
+	Settings
+
+	// Atomic runs fn against a view of the store whose writes are either
+	// all applied or, when fn returns an error, all discarded. Calling
+	// Atomic on that view again just runs fn in the same transaction.
+	// The memory and SQL stores make this a real transaction; Supabase
+	// can only revert the writes afterwards, see its Atomic.
+	Atomic(fn func(tx Storage) error) error
+}
+
+type Players interface {
+	CreatePlayer(player *types.Player) (*types.Player, error)
+	GetPlayer(id int) (*types.Player, error)
+	// ListPlayers lists every player, oldest first.
+	ListPlayers() ([]*types.Player, error)
+	// AddPlayerXP adds delta (which may be negative) to the player's XP,
+	// never letting it drop below zero, and returns the updated player.
+	AddPlayerXP(id int, delta int) (*types.Player, error)
+	// AddPlayerStats adds the given stats and stat points (both may be
+	// negative) and fails with ErrConstraint if stat points would drop
+	// below zero.
+	AddPlayerStats(id int, stats types.Stats, statPoints int) (*types.Player, error)
+	// LevelUpPlayer moves max_level from one level to another and grants
+	// statPoints, but only if max_level is still from. It reports whether
+	// the update happened.
+	LevelUpPlayer(id int, from, to int, statPoints int) (bool, error)
+	// SpendPlayerMana stores the player's mana as of at, but only if the
+	// stored mana is still fromMana as of fromAt, nil when the player
+	// never spent any. It reports whether the update happened.
+	SpendPlayerMana(id int, fromMana int, fromAt *time.Time, mana int, at time.Time) (bool, error)
+	// RecordPlayerStreak raises the player's longest streak to streak when
+	// it is longer.
+	RecordPlayerStreak(id int, streak int) error
+	// UpdatePlayerTitle sets the title shown in the status window.
+	UpdatePlayerTitle(id int, title string) error
+	// UpdatePlayerSchedule sets when the player's days start, together
+	// with the schedule it replaces and when it takes over.
+	UpdatePlayerSchedule(id int, schedule types.Schedule) error
+}
+
+type Quests interface {
+	CreateQuest(quest *types.Quest) (*types.Quest, error)
+	GetQuest(id int) (*types.Quest, error)
+	// GetQuestByTitle only looks at the quests everyone gets.
+	GetQuestByTitle(title string) (*types.Quest, error)
+	// ListQuests lists the quests everyone gets, leaving out the ones
+	// players made for themselves and archived ones.
+	ListQuests(kind QuestKind) ([]types.Quest, error)
+	// ListCustomQuests lists the quests the player made for themselves,
+	// leaving out archived ones.
+	ListCustomQuests(ownerID int, kind QuestKind) ([]types.Quest, error)
+	// UpdateQuest overwrites the catalogue fields of the quest with quest.ID.
+	// It doesn't change the owner.
+	UpdateQuest(quest *types.Quest) error
+	// ArchiveQuest stops the quest from being handed out.
+	ArchiveQuest(id int) error
+}
+
+type Skills interface {
+	CreateSkill(skill *types.Skill) (*types.Skill, error)
+	GetSkill(id int) (*types.Skill, error)
+	GetSkillByName(name string) (*types.Skill, error)
+	ListSkills() ([]*types.Skill, error)
+	// UpdateSkill overwrites the catalogue fields of the skill with skill.ID.
+	UpdateSkill(skill *types.Skill) error
+}
+
+type PlayerQuests interface {
+	CreatePlayerQuest(pq *types.PlayerQuest) (*types.PlayerQuest, error)
+	// ListPlayerQuests returns the matching rows, newest start_at first.
+	ListPlayerQuests(filter PlayerQuestFilter) ([]*types.PlayerQuest, error)
+	// UpdatePlayerQuestStatus sets status on every matching row and
+	// returns how many rows changed.
+	UpdatePlayerQuestStatus(filter PlayerQuestFilter, status int) (int, error)
+	UpdatePlayerQuestProgress(id int, progress []float64) error
+	// CountPlayerQuests counts the matching rows by status, ignoring the
+	// filter's Limit and After.
+	CountPlayerQuests(filter PlayerQuestFilter) (map[int]int, error)
+}
+
+type PlayerSkills interface {
+	CreatePlayerSkill(ps *types.PlayerSkills) (*types.PlayerSkills, error)
+	GetPlayerSkill(playerID, skillID int) (*types.PlayerSkills, error)
+	ListPlayerSkills(playerID int) ([]*types.PlayerSkills, error)
+	UpdatePlayerSkillProgress(id int, level, xp int) error
+}
+
+type SkillUsages interface {
+	CreateSkillUsage(usage *types.SkillUsage) (*types.SkillUsage, error)
+	// LastSkillUsage returns the player's most recent use of the skill or
+	// ErrNotFound if they never used it.
+	LastSkillUsage(playerID, skillID int) (*types.SkillUsage, error)
+}
+
+type PlayerChains interface {
+	// ListPlayerChains lists how far the player got in every chain they
+	// started.
+	ListPlayerChains(playerID int) ([]*types.PlayerChain, error)
+	// AdvancePlayerChain records that the player completed step of the
+	// chain. Progress never goes backwards.
+	AdvancePlayerChain(playerID int, chain string, step int) error
+}
+
+type Settings interface {
+	// InitSetting stores value under name unless something already is,
+	// and returns what is stored.
+	InitSetting(name, value string) (string, error)
+}
+
+// QuestKind splits quests the same way the roll does: among daily quests
+// priority 1 is the main quest, anything above it is a side quest and
+// priority 0 is a Penalty Zone quest. Weekly and monthly quests are kinds
+// of their own whatever their priority, and so are the event quests that
+// triggers hand out.
+type QuestKind int
+
+const (
+	AnyQuest QuestKind = iota
+	MainQuests
+	SideQuests
+	PenaltyQuests
+	WeeklyQuests
+	MonthlyQuests
+	EventQuests
+)
+
+// PlayerQuestFilter narrows player_quests queries. Zero values mean
+// "don't filter on this column".
+type PlayerQuestFilter struct {
+	ID            int
+	PlayerID      int
+	QuestID       int
+	Status        []int
+	Priority      []int
+	Kind          QuestKind
+	StartedBefore time.Time
+	// StartedAfter matches quests given at or after it.
+	StartedAfter time.Time
+	// DeadlineBefore matches quests whose deadline has passed by then.
+	DeadlineBefore time.Time
+	// After matches the rows listed after the one it points at.
+	After *PlayerQuestCursor
+	Limit int
+}
+
+// PlayerQuestCursor points at a player_quests row by the start_at and id
+// the rows are listed by.
+type PlayerQuestCursor struct {
+	StartAt time.Time
+	ID      int
+}
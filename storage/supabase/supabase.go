@@ -0,0 +1,1053 @@
+package supabase
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/MultiX0/solo_leveling_system/storage"
+	"github.com/MultiX0/solo_leveling_system/types"
+	"github.com/supabase-community/postgrest-go"
+	"github.com/supabase-community/supabase-go"
+)
+
+const timeLayout = "2006-01-02T15:04:05.999999Z"
+
+type Store struct {
+	client *supabase.Client
+	// undo is set inside Atomic and collects the compensating writes
+	// for everything done so far.
+	undo *[]func() error
+}
+
+func New(url, key string) (*Store, error) {
+	client, err := supabase.NewClient(url, key, &supabase.ClientOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	return &Store{client: client}, nil
+}
+
+// postgrest-go flattens error responses into "(code) message", so the
+// only way to tell them apart is the code prefix.
+func mapError(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	switch {
+	case strings.HasPrefix(err.Error(), "(PGRST116)"):
+		return storage.ErrNotFound
+	case strings.HasPrefix(err.Error(), "(23505)"):
+		return storage.ErrConflict
+	case strings.HasPrefix(err.Error(), "(23503)"), strings.HasPrefix(err.Error(), "(23514)"):
+		return storage.ErrConstraint
+	}
+
+	return err
+}
+
+// Atomic can't open a real transaction, PostgREST runs every request on
+// its own. Instead each write made through tx records how to revert it,
+// and those are replayed newest first when fn fails. That is weaker than
+// a transaction: other requests see the writes before they are reverted,
+// and a revert that fails leaves them in place. Failed reverts are logged
+// and returned together with fn's error.
+func (s *Store) Atomic(fn func(tx storage.Storage) error) error {
+	if s.undo != nil {
+		return fn(s)
+	}
+
+	undo := []func() error{}
+	if err := fn(&Store{client: s.client, undo: &undo}); err != nil {
+		errs := []error{err}
+		for i := len(undo) - 1; i >= 0; i-- {
+			if undoErr := undo[i](); undoErr != nil {
+				log.Printf("supabase: reverting a write failed, it is left in place: %v", undoErr)
+				errs = append(errs, fmt.Errorf("rollback: %w", undoErr))
+			}
+		}
+		return errors.Join(errs...)
+	}
+
+	return nil
+}
+
+// execute runs a write and only reports whether it failed.
+func execute(query *postgrest.FilterBuilder) error {
+	_, _, err := query.Execute()
+	return mapError(err)
+}
+
+func (s *Store) onRollback(f func() error) {
+	if s.undo != nil {
+		*s.undo = append(*s.undo, f)
+	}
+}
+
+func (s *Store) insert(table string, data any, out any) error {
+	newData, _, err := s.client.From(table).Insert(data, false, "", "", "exact").Single().Execute()
+	if err != nil {
+		return mapError(err)
+	}
+
+	var row struct {
+		ID int `json:"id"`
+	}
+	if err = json.Unmarshal(newData, &row); err != nil {
+		return err
+	}
+
+	s.onRollback(func() error {
+		return execute(s.client.From(table).Delete("", "").Eq("id", strconv.Itoa(row.ID)))
+	})
+
+	return json.Unmarshal(newData, out)
+}
+
+func (s *Store) single(table, column, value string, out any) error {
+	data, _, err := s.client.From(table).Select("*", "", false).Eq(column, value).Single().Execute()
+	if err != nil {
+		return mapError(err)
+	}
+
+	return json.Unmarshal(data, out)
+}
+
+func (s *Store) CreatePlayer(player *types.Player) (*types.Player, error) {
+	var newPlayer types.Player
+	err := s.insert("players", map[string]any{
+		"name":   player.Name,
+		"gender": player.Gender,
+	}, &newPlayer)
+	if err != nil {
+		return nil, err
+	}
+
+	return &newPlayer, nil
+}
+
+func (s *Store) GetPlayer(id int) (*types.Player, error) {
+	var player types.Player
+	if err := s.single("players", "id", strconv.Itoa(id), &player); err != nil {
+		return nil, err
+	}
+
+	return &player, nil
+}
+
+func (s *Store) ListPlayers() ([]*types.Player, error) {
+	data, _, err := s.client.From("players").Select("*", "", false).
+		Order("id", &postgrest.OrderOpts{Ascending: true}).Execute()
+	if err != nil {
+		return nil, mapError(err)
+	}
+
+	var players []*types.Player
+	if err = json.Unmarshal(data, &players); err != nil {
+		return nil, err
+	}
+
+	return players, nil
+}
+
+// maxPlayerRetries bounds how often changePlayer starts over when other
+// writes keep getting in between.
+const maxPlayerRetries = 10
+
+// counterRow is the columns of a player that are changed relative to
+// what they hold.
+func counterRow(player *types.Player) map[string]int {
+	return map[string]int{
+		"xp":             player.XP,
+		"strength":       player.Strength,
+		"agility":        player.Agility,
+		"sense":          player.Sense,
+		"vitality":       player.Vitality,
+		"intelligence":   player.Intelligence,
+		"stat_points":    player.StatPoints,
+		"max_level":      player.MaxLevel,
+		"longest_streak": player.LongestStreak,
+	}
+}
+
+// changePlayer is how counters like xp are changed, since PostgREST can't
+// express "xp = xp + delta". It reads the player, lets change update it
+// and writes the counters that changed, but only while they still hold
+// what was read, starting over when another write got in first. A change
+// that leaves the counters alone writes nothing.
+func (s *Store) changePlayer(id int, change func(player *types.Player) error) (*types.Player, error) {
+	for range maxPlayerRetries {
+		player, err := s.GetPlayer(id)
+		if err != nil {
+			return nil, err
+		}
+
+		read := counterRow(player)
+		if err = change(player); err != nil {
+			return nil, err
+		}
+
+		values := map[string]any{}
+		for column, value := range counterRow(player) {
+			if value != read[column] {
+				values[column] = value
+			}
+		}
+		if len(values) == 0 {
+			return player, nil
+		}
+
+		query := s.client.From("players").Update(values, "minimal", "exact").Eq("id", strconv.Itoa(id))
+		for column := range values {
+			query = query.Eq(column, strconv.Itoa(read[column]))
+		}
+
+		_, count, err := query.Execute()
+		if err != nil {
+			return nil, mapError(err)
+		}
+		if count > 0 {
+			return player, nil
+		}
+	}
+
+	return nil, storage.ErrConflict
+}
+
+// revertPlayer undoes a change made through changePlayer by applying
+// revert to the player as it is by then, keeping what other writes did
+// in the meantime.
+func (s *Store) revertPlayer(id int, revert func(player *types.Player) error) {
+	s.onRollback(func() error {
+		_, err := s.changePlayer(id, revert)
+		return err
+	})
+}
+
+func (s *Store) AddPlayerXP(id int, delta int) (*types.Player, error) {
+	var applied int
+	player, err := s.changePlayer(id, func(player *types.Player) error {
+		xp := max(player.XP+delta, 0)
+		applied = xp - player.XP
+		player.XP = xp
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.revertPlayer(id, func(player *types.Player) error {
+		player.XP = max(player.XP-applied, 0)
+		return nil
+	})
+
+	return player, nil
+}
+
+// updatePlayer sets columns that aren't counters. Reverting it leaves the
+// columns alone when another write changed them since.
+func (s *Store) updatePlayer(id int, values, previous map[string]any) error {
+	_, _, err := s.client.From("players").Update(values, "minimal", "").
+		Eq("id", strconv.Itoa(id)).Execute()
+	if err != nil {
+		return mapError(err)
+	}
+
+	s.onRollback(func() error {
+		query := s.client.From("players").Update(previous, "minimal", "").Eq("id", strconv.Itoa(id))
+		for column, value := range values {
+			if value == nil {
+				query = query.Is(column, "null")
+			} else {
+				query = query.Eq(column, filterValue(value))
+			}
+		}
+		return execute(query)
+	})
+
+	return nil
+}
+
+func filterValue(value any) string {
+	if t, ok := value.(time.Time); ok {
+		return t.UTC().Format(timeLayout)
+	}
+	return fmt.Sprint(value)
+}
+
+func addStats(player *types.Player, stats types.Stats, statPoints int) {
+	player.Strength += stats.Strength
+	player.Agility += stats.Agility
+	player.Sense += stats.Sense
+	player.Vitality += stats.Vitality
+	player.Intelligence += stats.Intelligence
+	player.StatPoints += statPoints
+}
+
+func (s *Store) AddPlayerStats(id int, stats types.Stats, statPoints int) (*types.Player, error) {
+	player, err := s.changePlayer(id, func(player *types.Player) error {
+		if player.StatPoints+statPoints < 0 {
+			return storage.ErrConstraint
+		}
+		addStats(player, stats, statPoints)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.revertPlayer(id, func(player *types.Player) error {
+		addStats(player, types.Stats{
+			Strength:     -stats.Strength,
+			Agility:      -stats.Agility,
+			Sense:        -stats.Sense,
+			Vitality:     -stats.Vitality,
+			Intelligence: -stats.Intelligence,
+		}, -statPoints)
+		return nil
+	})
+
+	return player, nil
+}
+
+func (s *Store) LevelUpPlayer(id int, from, to int, statPoints int) (bool, error) {
+	leveled := false
+	_, err := s.changePlayer(id, func(player *types.Player) error {
+		leveled = player.MaxLevel == from
+		if leveled {
+			player.MaxLevel = to
+			player.StatPoints += statPoints
+		}
+		return nil
+	})
+	if err != nil {
+		return false, err
+	}
+
+	if leveled {
+		s.revertPlayer(id, func(player *types.Player) error {
+			if player.MaxLevel == to {
+				player.MaxLevel = from
+				player.StatPoints -= statPoints
+			}
+			return nil
+		})
+	}
+
+	return leveled, nil
+}
+
+func (s *Store) SpendPlayerMana(id int, fromMana int, fromAt *time.Time, mana int, at time.Time) (bool, error) {
+	manaFilter := func(query *postgrest.FilterBuilder, mana int, at *time.Time) *postgrest.FilterBuilder {
+		query = query.Eq("id", strconv.Itoa(id)).Eq("mana", strconv.Itoa(mana))
+		if at == nil {
+			return query.Is("mana_updated_at", "null")
+		}
+		return query.Eq("mana_updated_at", filterValue(*at))
+	}
+
+	at = at.UTC()
+	_, count, err := manaFilter(s.client.From("players").Update(map[string]any{"mana": mana, "mana_updated_at": at}, "minimal", "exact"),
+		fromMana, fromAt).Execute()
+	if err != nil {
+		return false, mapError(err)
+	}
+	if count == 0 {
+		return false, nil
+	}
+
+	s.onRollback(func() error {
+		return execute(manaFilter(s.client.From("players").Update(map[string]any{"mana": fromMana, "mana_updated_at": fromAt}, "minimal", ""),
+			mana, &at))
+	})
+
+	return true, nil
+}
+
+func (s *Store) RecordPlayerStreak(id int, streak int) error {
+	var previous int
+	recorded := false
+	_, err := s.changePlayer(id, func(player *types.Player) error {
+		previous = player.LongestStreak
+		recorded = player.LongestStreak < streak
+		if recorded {
+			player.LongestStreak = streak
+		}
+		return nil
+	})
+	if err != nil || !recorded {
+		return err
+	}
+
+	s.revertPlayer(id, func(player *types.Player) error {
+		if player.LongestStreak == streak {
+			player.LongestStreak = previous
+		}
+		return nil
+	})
+
+	return nil
+}
+
+func (s *Store) UpdatePlayerTitle(id int, title string) error {
+	player, err := s.GetPlayer(id)
+	if err != nil {
+		return err
+	}
+
+	return s.updatePlayer(id,
+		map[string]any{"title": title},
+		map[string]any{"title": player.Title})
+}
+
+func (s *Store) UpdatePlayerSchedule(id int, schedule types.Schedule) error {
+	player, err := s.GetPlayer(id)
+	if err != nil {
+		return err
+	}
+
+	return s.updatePlayer(id, scheduleRow(schedule), scheduleRow(player.Schedule))
+}
+
+func scheduleRow(schedule types.Schedule) map[string]any {
+	var from any
+	if schedule.ScheduleFrom != nil {
+		from = schedule.ScheduleFrom.UTC().Format(timeLayout)
+	}
+
+	return map[string]any{
+		"time_zone":           schedule.TimeZone,
+		"reset_hour":          schedule.ResetHour,
+		"schedule_from":       from,
+		"previous_time_zone":  schedule.PreviousTimeZone,
+		"previous_reset_hour": schedule.PreviousResetHour,
+	}
+}
+
+func (s *Store) CreateQuest(quest *types.Quest) (*types.Quest, error) {
+	row := questRow(quest)
+	if quest.Owner != 0 {
+		row["owner"] = quest.Owner
+	}
+
+	var newQuest types.Quest
+	err := s.insert("quests", row, &newQuest)
+	if err != nil {
+		return nil, err
+	}
+
+	return &newQuest, nil
+}
+
+func (s *Store) UpdateQuest(quest *types.Quest) error {
+	previous, err := s.GetQuest(quest.ID)
+	if err != nil {
+		return err
+	}
+
+	_, _, err = s.client.From("quests").Update(questRow(quest), "minimal", "").
+		Eq("id", strconv.Itoa(quest.ID)).Execute()
+	if err != nil {
+		return mapError(err)
+	}
+
+	s.onRollback(func() error {
+		return execute(s.client.From("quests").Update(questRow(previous), "minimal", "").
+			Eq("id", strconv.Itoa(quest.ID)))
+	})
+
+	return nil
+}
+
+func (s *Store) ArchiveQuest(id int) error {
+	if _, err := s.GetQuest(id); err != nil {
+		return err
+	}
+
+	_, _, err := s.client.From("quests").Update(map[string]any{"archived": true}, "minimal", "").
+		Eq("id", strconv.Itoa(id)).Execute()
+	if err != nil {
+		return mapError(err)
+	}
+
+	s.onRollback(func() error {
+		return execute(s.client.From("quests").Update(map[string]any{"archived": false}, "minimal", "").
+			Eq("id", strconv.Itoa(id)))
+	})
+
+	return nil
+}
+
+// questRow leaves out the owner, which only CreateQuest sets.
+func questRow(quest *types.Quest) map[string]any {
+	return map[string]any{
+		"title":       quest.Title,
+		"description": quest.Description,
+		"priority":    quest.Priority,
+		"objectives":  quest.Objectives,
+		"cadence":     questCadence(quest.Cadence),
+		"chain":       questChain(quest),
+		"chain_step":  quest.ChainStep,
+		"trigger":     quest.Trigger,
+		"category":    questCategory(quest),
+		"weight":      questWeight(quest),
+	}
+}
+
+// questCategory stores quests outside of any category with a NULL
+// category.
+func questCategory(quest *types.Quest) any {
+	if quest.Category == "" {
+		return nil
+	}
+	return quest.Category
+}
+
+func questWeight(quest *types.Quest) int {
+	if quest.Weight <= 0 {
+		return types.DefaultQuestWeight
+	}
+	return quest.Weight
+}
+
+// questChain stores quests outside of any chain with a NULL chain.
+func questChain(quest *types.Quest) any {
+	if quest.Chain == "" {
+		return nil
+	}
+	return quest.Chain
+}
+
+func (s *Store) GetQuest(id int) (*types.Quest, error) {
+	var quest types.Quest
+	if err := s.single("quests", "id", strconv.Itoa(id), &quest); err != nil {
+		return nil, err
+	}
+
+	return &quest, nil
+}
+
+func (s *Store) GetQuestByTitle(title string) (*types.Quest, error) {
+	data, _, err := s.client.From("quests").Select("*", "exact", false).Eq("title", title).Is("owner", "null").Limit(1, "").Execute()
+	if err != nil {
+		return nil, mapError(err)
+	}
+
+	var quests []types.Quest
+	if err = json.Unmarshal(data, &quests); err != nil {
+		return nil, err
+	}
+
+	if len(quests) == 0 {
+		return nil, storage.ErrNotFound
+	}
+
+	return &quests[0], nil
+}
+
+func (s *Store) ListQuests(kind storage.QuestKind) ([]types.Quest, error) {
+	return s.listQuests(s.client.From("quests").Select("*", "exact", false).Is("owner", "null"), kind)
+}
+
+func (s *Store) ListCustomQuests(ownerID int, kind storage.QuestKind) ([]types.Quest, error) {
+	return s.listQuests(s.client.From("quests").Select("*", "exact", false).Eq("owner", strconv.Itoa(ownerID)), kind)
+}
+
+func (s *Store) listQuests(query *postgrest.FilterBuilder, kind storage.QuestKind) ([]types.Quest, error) {
+	data, _, err := filterKind(query.Is("archived", "false"), kind).Order("id", &postgrest.OrderOpts{Ascending: true}).Execute()
+	if err != nil {
+		return nil, mapError(err)
+	}
+
+	var quests []types.Quest
+	if err = json.Unmarshal(data, &quests); err != nil {
+		return nil, err
+	}
+
+	return quests, nil
+}
+
+func (s *Store) CreateSkill(skill *types.Skill) (*types.Skill, error) {
+	var newSkill types.Skill
+	err := s.insert("skills", skillRow(skill), &newSkill)
+	if err != nil {
+		return nil, err
+	}
+
+	return &newSkill, nil
+}
+
+func (s *Store) GetSkill(id int) (*types.Skill, error) {
+	var skill types.Skill
+	if err := s.single("skills", "id", strconv.Itoa(id), &skill); err != nil {
+		return nil, err
+	}
+
+	return &skill, nil
+}
+
+func (s *Store) GetSkillByName(name string) (*types.Skill, error) {
+	data, _, err := s.client.From("skills").Select("*", "exact", false).Eq("name", name).Limit(1, "").Execute()
+	if err != nil {
+		return nil, mapError(err)
+	}
+
+	var skills []types.Skill
+	if err = json.Unmarshal(data, &skills); err != nil {
+		return nil, err
+	}
+
+	if len(skills) == 0 {
+		return nil, storage.ErrNotFound
+	}
+
+	return &skills[0], nil
+}
+
+func (s *Store) ListSkills() ([]*types.Skill, error) {
+	data, _, err := s.client.From("skills").Select("*", "", false).Order("id", &postgrest.OrderOpts{Ascending: true}).Execute()
+	if err != nil {
+		return nil, mapError(err)
+	}
+
+	var skills []*types.Skill
+	if err = json.Unmarshal(data, &skills); err != nil {
+		return nil, err
+	}
+
+	return skills, nil
+}
+
+func (s *Store) UpdateSkill(skill *types.Skill) error {
+	previous, err := s.GetSkill(skill.ID)
+	if err != nil {
+		return err
+	}
+
+	_, _, err = s.client.From("skills").Update(skillRow(skill), "minimal", "").
+		Eq("id", strconv.Itoa(skill.ID)).Execute()
+	if err != nil {
+		return mapError(err)
+	}
+
+	s.onRollback(func() error {
+		return execute(s.client.From("skills").Update(skillRow(previous), "minimal", "").
+			Eq("id", strconv.Itoa(skill.ID)))
+	})
+
+	return nil
+}
+
+func skillRow(skill *types.Skill) map[string]any {
+	return map[string]any{
+		"name":        skill.Name,
+		"description": skill.Description,
+		"level":       skill.Level,
+		"max_level":   skill.MaxLevel,
+		"requires":    skill.Requires,
+		"type":        skill.Type,
+		"cooldown":    skill.Cooldown,
+		"mana_cost":   skill.ManaCost,
+		"effects":     skill.Effects,
+	}
+}
+
+func (s *Store) CreatePlayerQuest(pq *types.PlayerQuest) (*types.PlayerQuest, error) {
+	startAt := pq.StartAt
+	if startAt.IsZero() {
+		startAt = time.Now()
+	}
+
+	deadline := pq.Deadline
+	if deadline.IsZero() {
+		deadline = startAt.Add(types.DefaultQuestDeadline)
+	}
+
+	row := map[string]any{
+		"start_at":    startAt.UTC().Format(timeLayout),
+		"deadline":    deadline.UTC().Format(timeLayout),
+		"progress":    pq.Progress,
+		"description": pq.Description,
+		"objectives":  pq.Objectives,
+		"player":      pq.PlayerID,
+		"quest":       pq.QuestID,
+		"status":      pq.Status,
+		"priority":    pq.Priority,
+		"cadence":     questCadence(pq.Cadence),
+	}
+	if pq.RerolledFrom != 0 {
+		row["rerolled_from"] = pq.RerolledFrom
+	}
+
+	var newPQ types.PlayerQuest
+	err := s.insert("player_quests", row, &newPQ)
+	if err != nil {
+		return nil, err
+	}
+
+	return &newPQ, nil
+}
+
+func filterKind(query *postgrest.FilterBuilder, kind storage.QuestKind) *postgrest.FilterBuilder {
+	switch kind {
+	case storage.MainQuests:
+		return query.Eq("priority", "1").Eq("cadence", types.CadenceDaily)
+	case storage.SideQuests:
+		return query.Gt("priority", "1").Eq("cadence", types.CadenceDaily)
+	case storage.PenaltyQuests:
+		return query.Eq("priority", "0").Eq("cadence", types.CadenceDaily)
+	case storage.WeeklyQuests:
+		return query.Eq("cadence", types.CadenceWeekly)
+	case storage.MonthlyQuests:
+		return query.Eq("cadence", types.CadenceMonthly)
+	case storage.EventQuests:
+		return query.Eq("cadence", types.CadenceEvent)
+	}
+	return query
+}
+
+func questCadence(cadence string) string {
+	if cadence == "" {
+		return types.CadenceDaily
+	}
+	return cadence
+}
+
+// quoteTime formats t to be used inside and=(...) and or=(...), where
+// the dots and colons of a bare timestamp would be taken as syntax.
+func quoteTime(t time.Time) string {
+	return `"` + t.UTC().Format(timeLayout) + `"`
+}
+
+func applyFilter(query *postgrest.FilterBuilder, filter storage.PlayerQuestFilter) *postgrest.FilterBuilder {
+	if filter.ID != 0 {
+		query = query.Eq("id", strconv.Itoa(filter.ID))
+	}
+	if filter.PlayerID != 0 {
+		query = query.Eq("player", strconv.Itoa(filter.PlayerID))
+	}
+	if filter.QuestID != 0 {
+		query = query.Eq("quest", strconv.Itoa(filter.QuestID))
+	}
+	if len(filter.Status) > 0 {
+		statuses := make([]string, len(filter.Status))
+		for i, status := range filter.Status {
+			statuses[i] = strconv.Itoa(status)
+		}
+		query = query.In("status", statuses)
+	}
+
+	if len(filter.Priority) > 0 {
+		priorities := make([]string, len(filter.Priority))
+		for i, priority := range filter.Priority {
+			priorities[i] = strconv.Itoa(priority)
+		}
+		query = query.In("priority", priorities)
+	}
+
+	query = filterKind(query, filter.Kind)
+
+	// the filters are keyed by column, so every condition on start_at goes
+	// into one and=(...) instead
+	var startAt []string
+	if !filter.StartedBefore.IsZero() {
+		startAt = append(startAt, "start_at.lt."+quoteTime(filter.StartedBefore))
+	}
+	if !filter.StartedAfter.IsZero() {
+		startAt = append(startAt, "start_at.gte."+quoteTime(filter.StartedAfter))
+	}
+	if filter.After != nil {
+		at := quoteTime(filter.After.StartAt)
+		startAt = append(startAt, "or(start_at.lt."+at+",and(start_at.eq."+at+",id.lt."+strconv.Itoa(filter.After.ID)+"))")
+	}
+	if len(startAt) > 0 {
+		query = query.And(strings.Join(startAt, ","), "")
+	}
+	if !filter.DeadlineBefore.IsZero() {
+		query = query.Lt("deadline", filter.DeadlineBefore.UTC().Format(timeLayout))
+	}
+
+	return query
+}
+
+func (s *Store) ListPlayerQuests(filter storage.PlayerQuestFilter) ([]*types.PlayerQuest, error) {
+	query := applyFilter(s.client.From("player_quests").Select("*", "exact", false), filter).
+		Order("start_at", &postgrest.OrderOpts{Ascending: false}).
+		Order("id", &postgrest.OrderOpts{Ascending: false})
+
+	if filter.Limit > 0 {
+		query = query.Limit(filter.Limit, "")
+	}
+
+	data, _, err := query.Execute()
+	if err != nil {
+		return nil, mapError(err)
+	}
+
+	var quests []*types.PlayerQuest
+	if err = json.Unmarshal(data, &quests); err != nil {
+		return nil, err
+	}
+
+	return quests, nil
+}
+
+func (s *Store) CountPlayerQuests(filter storage.PlayerQuestFilter) (map[int]int, error) {
+	filter.After = nil
+
+	statuses := filter.Status
+	if len(statuses) == 0 {
+		statuses = []int{types.QuestAbandoned, types.QuestActive, types.QuestCompleted, types.QuestExpired}
+	}
+
+	// the server counts each status, fetching the rows would stop at its
+	// max-rows
+	counts := map[int]int{}
+	for _, status := range statuses {
+		filter.Status = []int{status}
+		_, count, err := applyFilter(s.client.From("player_quests").Select("id", "exact", true), filter).Execute()
+		if err != nil {
+			return nil, mapError(err)
+		}
+		if count > 0 {
+			counts[status] = int(count)
+		}
+	}
+
+	return counts, nil
+}
+
+func (s *Store) UpdatePlayerQuestStatus(filter storage.PlayerQuestFilter, status int) (int, error) {
+	if s.undo != nil {
+		previous, err := s.ListPlayerQuests(filter)
+		if err != nil {
+			return 0, err
+		}
+		s.onRollback(func() error {
+			var errs []error
+			for _, pq := range previous {
+				errs = append(errs, execute(s.client.From("player_quests").Update(map[string]any{"status": pq.Status}, "minimal", "").
+					Eq("id", strconv.Itoa(pq.ID))))
+			}
+			return errors.Join(errs...)
+		})
+	}
+
+	query := s.client.From("player_quests").Update(map[string]any{"status": status}, "", "exact")
+
+	_, count, err := applyFilter(query, filter).Execute()
+	if err != nil {
+		return 0, mapError(err)
+	}
+
+	return int(count), nil
+}
+
+func (s *Store) UpdatePlayerQuestProgress(id int, progress []float64) error {
+	var previous types.PlayerQuest
+	if err := s.single("player_quests", "id", strconv.Itoa(id), &previous); err != nil {
+		return err
+	}
+
+	_, _, err := s.client.From("player_quests").Update(map[string]any{"progress": progress}, "minimal", "").
+		Eq("id", strconv.Itoa(id)).Execute()
+	if err != nil {
+		return mapError(err)
+	}
+
+	s.onRollback(func() error {
+		return execute(s.client.From("player_quests").Update(map[string]any{"progress": previous.Progress}, "minimal", "").
+			Eq("id", strconv.Itoa(id)))
+	})
+
+	return nil
+}
+
+func (s *Store) CreatePlayerSkill(ps *types.PlayerSkills) (*types.PlayerSkills, error) {
+	var newPS types.PlayerSkills
+	err := s.insert("player_skills", map[string]any{
+		"skill":  ps.SkillID,
+		"player": ps.PlayerID,
+		"level":  max(ps.Level, 1),
+		"xp":     ps.XP,
+	}, &newPS)
+	if err != nil {
+		return nil, err
+	}
+
+	return &newPS, nil
+}
+
+func (s *Store) GetPlayerSkill(playerID, skillID int) (*types.PlayerSkills, error) {
+	data, _, err := s.client.From("player_skills").Select("*", "", false).
+		Eq("player", strconv.Itoa(playerID)).Eq("skill", strconv.Itoa(skillID)).Execute()
+	if err != nil {
+		return nil, mapError(err)
+	}
+
+	var skills []*types.PlayerSkills
+	if err = json.Unmarshal(data, &skills); err != nil {
+		return nil, err
+	}
+
+	if len(skills) == 0 {
+		return nil, storage.ErrNotFound
+	}
+
+	return skills[0], nil
+}
+
+func (s *Store) UpdatePlayerSkillProgress(id int, level, xp int) error {
+	var previous types.PlayerSkills
+	if err := s.single("player_skills", "id", strconv.Itoa(id), &previous); err != nil {
+		return err
+	}
+
+	_, _, err := s.client.From("player_skills").Update(map[string]any{"level": level, "xp": xp}, "minimal", "").
+		Eq("id", strconv.Itoa(id)).Execute()
+	if err != nil {
+		return mapError(err)
+	}
+
+	s.onRollback(func() error {
+		return execute(s.client.From("player_skills").Update(map[string]any{"level": previous.Level, "xp": previous.XP}, "minimal", "").
+			Eq("id", strconv.Itoa(id)))
+	})
+
+	return nil
+}
+
+func (s *Store) ListPlayerSkills(playerID int) ([]*types.PlayerSkills, error) {
+	data, _, err := s.client.From("player_skills").Select("*", "", false).Eq("player", strconv.Itoa(playerID)).Execute()
+	if err != nil {
+		return nil, mapError(err)
+	}
+
+	var skills []*types.PlayerSkills
+	if err = json.Unmarshal(data, &skills); err != nil {
+		return nil, err
+	}
+
+	return skills, nil
+}
+
+func (s *Store) CreateSkillUsage(usage *types.SkillUsage) (*types.SkillUsage, error) {
+	usedAt := usage.UsedAt
+	if usedAt.IsZero() {
+		usedAt = time.Now()
+	}
+
+	var newUsage types.SkillUsage
+	err := s.insert("skill_usages", map[string]any{
+		"player":    usage.PlayerID,
+		"skill":     usage.SkillID,
+		"used_at":   usedAt.UTC(),
+		"mana_cost": usage.ManaCost,
+	}, &newUsage)
+	if err != nil {
+		return nil, err
+	}
+
+	return &newUsage, nil
+}
+
+func (s *Store) LastSkillUsage(playerID, skillID int) (*types.SkillUsage, error) {
+	data, _, err := s.client.From("skill_usages").Select("*", "", false).
+		Eq("player", strconv.Itoa(playerID)).Eq("skill", strconv.Itoa(skillID)).
+		Order("used_at", &postgrest.OrderOpts{Ascending: false}).Limit(1, "").Execute()
+	if err != nil {
+		return nil, mapError(err)
+	}
+
+	var usages []*types.SkillUsage
+	if err = json.Unmarshal(data, &usages); err != nil {
+		return nil, err
+	}
+
+	if len(usages) == 0 {
+		return nil, storage.ErrNotFound
+	}
+
+	return usages[0], nil
+}
+
+func (s *Store) ListPlayerChains(playerID int) ([]*types.PlayerChain, error) {
+	data, _, err := s.client.From("player_chains").Select("*", "", false).
+		Eq("player", strconv.Itoa(playerID)).
+		Order("chain", &postgrest.OrderOpts{Ascending: true}).Execute()
+	if err != nil {
+		return nil, mapError(err)
+	}
+
+	var chains []*types.PlayerChain
+	if err = json.Unmarshal(data, &chains); err != nil {
+		return nil, err
+	}
+
+	return chains, nil
+}
+
+// PostgREST upserts can't keep the higher step, so this reads the chain
+// and only writes when the player got further.
+func (s *Store) AdvancePlayerChain(playerID int, chain string, step int) error {
+	chains, err := s.ListPlayerChains(playerID)
+	if err != nil {
+		return err
+	}
+
+	for _, pc := range chains {
+		if pc.Chain != chain {
+			continue
+		}
+		if pc.Step >= step {
+			return nil
+		}
+
+		_, _, err = s.client.From("player_chains").Update(map[string]any{
+			"step":       step,
+			"updated_at": time.Now().UTC(),
+		}, "minimal", "").Eq("id", strconv.Itoa(pc.ID)).Execute()
+		if err != nil {
+			return mapError(err)
+		}
+
+		s.onRollback(func() error {
+			return execute(s.client.From("player_chains").Update(map[string]any{
+				"step":       pc.Step,
+				"updated_at": pc.UpdatedAt,
+			}, "minimal", "").Eq("id", strconv.Itoa(pc.ID)))
+		})
+		return nil
+	}
+
+	var newChain types.PlayerChain
+	return s.insert("player_chains", map[string]any{
+		"player":     playerID,
+		"chain":      chain,
+		"step":       step,
+		"updated_at": time.Now().UTC(),
+	}, &newChain)
+}
+
+func (s *Store) InitSetting(name, value string) (string, error) {
+	_, _, err := s.client.From("settings").Insert(map[string]any{"name": name, "value": value}, false, "", "minimal", "").Execute()
+	// someone else stored one first
+	if err = mapError(err); err != nil && !errors.Is(err, storage.ErrConflict) {
+		return "", err
+	}
+
+	var row struct {
+		Value string `json:"value"`
+	}
+	if err = s.single("settings", "name", name, &row); err != nil {
+		return "", err
+	}
+
+	return row.Value, nil
+}
@@ -7,6 +7,77 @@ type Quest struct {
 	Title       string `json:"title"`
 	Description string `json:"description"`
 	Priority    int    `json:"priority"`
+	// Category groups similar quests. Side quests given together come from
+	// different categories whenever the pool allows it.
+	Category string `json:"category,omitempty"`
+	// Weight is how likely the quest is picked compared to the others of its
+	// kind, DefaultQuestWeight when it isn't set.
+	Weight int `json:"weight,omitempty"`
+	// Objectives are the countable parts of the quest. Quests without
+	// objectives can only be finished all at once.
+	Objectives []Objective `json:"objectives,omitempty"`
+	// Cadence is how often the quest is handed out, CadenceDaily when it
+	// isn't set.
+	Cadence string `json:"cadence,omitempty"`
+	// Owner is the player who made the quest for themselves, zero for the
+	// quests everyone gets.
+	Owner int `json:"owner,omitempty"`
+	// Archived quests aren't handed out anymore but stay around for the
+	// player_quests that point at them.
+	Archived bool `json:"archived,omitempty"`
+	// Chain names the story arc the quest is step ChainStep of. Completing
+	// a step unlocks the next one.
+	Chain     string `json:"chain,omitempty"`
+	ChainStep int    `json:"chain_step,omitempty"`
+	// Trigger says when a CadenceEvent quest is handed out.
+	Trigger *QuestTrigger `json:"trigger,omitempty"`
+}
+
+// DefaultQuestWeight is the weight of quests that don't set one.
+const DefaultQuestWeight = 10
+
+const (
+	CadenceDaily   = "daily"
+	CadenceWeekly  = "weekly"
+	CadenceMonthly = "monthly"
+	// CadenceEvent quests are only handed out when their trigger fires.
+	CadenceEvent = "event"
+)
+
+// QuestTrigger hands out an event quest once every condition holds. Zero
+// values mean "no condition".
+type QuestTrigger struct {
+	// Type is TriggerEmergency or TriggerHidden.
+	Type   string `json:"type"`
+	Streak int    `json:"streak,omitempty"`
+	Level  int    `json:"level,omitempty"`
+	Skill  string `json:"skill,omitempty"`
+	// Chance is the odds, from 0 to 1, that the quest is given when the
+	// conditions are checked and hold. Zero means every time.
+	Chance float64 `json:"chance,omitempty"`
+	// Hours is how long the player has to finish the quest.
+	Hours int `json:"hours"`
+	// Multiplier scales the XP the quest gives, 1 when it isn't set.
+	Multiplier int `json:"multiplier,omitempty"`
+}
+
+const (
+	// TriggerEmergency quests come with a short deadline and can come back.
+	TriggerEmergency = "emergency"
+	// TriggerHidden quests are given once, when their conditions are met.
+	TriggerHidden = "hidden"
+)
+
+// Objective is one countable goal of a quest, e.g. 100 push-ups.
+type Objective struct {
+	Name   string  `json:"name"`
+	Target float64 `json:"target"`
+	Unit   string  `json:"unit"`
+	// PerLevel makes the target a template: the player's level times
+	// PerLevel is added to Target when the quest is given, up to Max when
+	// Max is set.
+	PerLevel float64 `json:"per_level,omitempty"`
+	Max      float64 `json:"max,omitempty"`
 }
 
 type Skill struct {
@@ -14,13 +85,108 @@ type Skill struct {
 	Name        string `json:"name"`
 	Description string `json:"description"`
 	Level       int    `json:"level"`
+	MaxLevel    int    `json:"max_level"`
+	// Requires lists what a player needs before the skill can drop.
+	Requires *SkillRequirements `json:"requires,omitempty"`
+	// Type is SkillActive or SkillPassive. Only active skills can be used,
+	// Cooldown is in seconds.
+	Type     string `json:"type"`
+	Cooldown int    `json:"cooldown,omitempty"`
+	ManaCost int    `json:"mana_cost,omitempty"`
+	// Effects only apply while the player owns the skill and it is passive.
+	Effects *SkillEffects `json:"effects,omitempty"`
+}
+
+// SkillEffects change how quests treat the player. Percentages are whole
+// numbers, e.g. 10 is +10%.
+type SkillEffects struct {
+	MainQuestXP         int `json:"main_quest_xp,omitempty"`
+	SideQuestXP         int `json:"side_quest_xp,omitempty"`
+	PunishmentReduction int `json:"punishment_reduction,omitempty"`
+	ExtraSideQuests     int `json:"extra_side_quests,omitempty"`
+	// DeadlineHours lets daily quests run past the daily reset.
+	DeadlineHours int `json:"deadline_hours,omitempty"`
+}
+
+// Add returns the sum of both effects.
+func (e SkillEffects) Add(o SkillEffects) SkillEffects {
+	return SkillEffects{
+		MainQuestXP:         e.MainQuestXP + o.MainQuestXP,
+		SideQuestXP:         e.SideQuestXP + o.SideQuestXP,
+		PunishmentReduction: e.PunishmentReduction + o.PunishmentReduction,
+		ExtraSideQuests:     e.ExtraSideQuests + o.ExtraSideQuests,
+		DeadlineHours:       e.DeadlineHours + o.DeadlineHours,
+	}
+}
+
+const (
+	SkillActive  = "active"
+	SkillPassive = "passive"
+)
+
+// SkillRequirements are the prerequisites of a skill. Other skills are
+// referenced by name since ids differ between databases.
+type SkillRequirements struct {
+	Skills []string `json:"skills,omitempty"`
+	Level  int      `json:"level,omitempty"`
+	// Stats holds minimum values, zero means no minimum.
+	Stats *Stats `json:"stats,omitempty"`
 }
 
+// DefaultSkillMaxLevel is used for catalogue entries without a max_level.
+const DefaultSkillMaxLevel = 10
+
 type Player struct {
 	ID       int       `json:"id"`
 	Name     string    `json:"name"`
 	Gender   bool      `json:"gender"`
 	JoinedAt time.Time `json:"joined_at"`
+	XP       int       `json:"xp"`
+	Stats
+	StatPoints int `json:"stat_points"`
+	// MaxLevel is the highest level the player has been granted stat
+	// points for, so levels lost to punishments aren't paid out twice.
+	MaxLevel int    `json:"max_level"`
+	Title    string `json:"title"`
+	// Mana is what the player had at ManaUpdatedAt, it regenerates from
+	// there. A nil ManaUpdatedAt means the player never spent any.
+	Mana          int        `json:"mana"`
+	ManaUpdatedAt *time.Time `json:"mana_updated_at,omitempty"`
+	// LongestStreak is the most days in a row the player ever completed
+	// their main quest.
+	LongestStreak int `json:"longest_streak"`
+	Schedule
+}
+
+// Schedule is when a player's days start.
+type Schedule struct {
+	// TimeZone is the IANA name of the player's time zone, their daily
+	// quests roll over at ResetHour o'clock there.
+	TimeZone  string `json:"time_zone"`
+	ResetHour int    `json:"reset_hour"`
+	// ScheduleFrom is when TimeZone and ResetHour took or take over from
+	// the previous schedule, nil when they were never changed.
+	ScheduleFrom      *time.Time `json:"schedule_from,omitempty"`
+	PreviousTimeZone  string     `json:"previous_time_zone,omitempty"`
+	PreviousResetHour int        `json:"previous_reset_hour,omitempty"`
+}
+
+// DefaultTimeZone is where new players' days start.
+const DefaultTimeZone = "UTC"
+
+type Stats struct {
+	Strength     int `json:"strength"`
+	Agility      int `json:"agility"`
+	Sense        int `json:"sense"`
+	Vitality     int `json:"vitality"`
+	Intelligence int `json:"intelligence"`
+}
+
+// BaseStat is what every stat starts at for a new player.
+const BaseStat = 10
+
+func (s Stats) Total() int {
+	return s.Strength + s.Agility + s.Sense + s.Vitality + s.Intelligence
 }
 
 type PlayerQuest struct {
@@ -29,11 +195,64 @@ type PlayerQuest struct {
 	PlayerID int       `json:"player"`
 	QuestID  int       `json:"quest"`
 	Status   int       `json:"status"`
+	Priority int       `json:"priority"`
+	Cadence  string    `json:"cadence,omitempty"`
+	// Deadline is when the quest expires if it is still active.
+	Deadline time.Time `json:"deadline"`
+	// Description and Objectives are the quest as it was rendered for the
+	// player's level when it was given.
+	Description string      `json:"description,omitempty"`
+	Objectives  []Objective `json:"objectives,omitempty"`
+	// Progress holds how much of each objective is done, in the same order
+	// as Objectives.
+	Progress []float64 `json:"progress,omitempty"`
+	// RerolledFrom is the player_quests row this quest replaced when the
+	// player rerolled it, zero otherwise.
+	RerolledFrom int `json:"rerolled_from,omitempty"`
 }
 
+// DefaultQuestDeadline is how long a quest stays active when it was given
+// without a deadline.
+const DefaultQuestDeadline = 24 * time.Hour
+
+// PenaltyPriority is the priority of Penalty Zone quests, which are only
+// given when a main quest is missed.
+const PenaltyPriority = 0
+
+// player_quests.status values
+const (
+	QuestAbandoned = -1
+	QuestActive    = 0
+	QuestCompleted = 1
+	QuestExpired   = 2
+)
+
 type PlayerSkills struct {
 	ID        int       `json:"id"`
 	SkillID   int       `json:"skill"`
 	PlayerID  int       `json:"player"`
 	RecivedAt time.Time `json:"recived_at"`
+	// Level and XP are the player's proficiency with the skill, not the
+	// catalogue level it is rolled at.
+	Level int `json:"level"`
+	XP    int `json:"xp"`
+}
+
+// SkillUsage records a player using one of their active skills.
+type SkillUsage struct {
+	ID       int       `json:"id"`
+	PlayerID int       `json:"player"`
+	SkillID  int       `json:"skill"`
+	UsedAt   time.Time `json:"used_at"`
+	ManaCost int       `json:"mana_cost"`
+}
+
+// PlayerChain is how far a player got in a quest chain.
+type PlayerChain struct {
+	ID       int    `json:"id"`
+	PlayerID int    `json:"player"`
+	Chain    string `json:"chain"`
+	// Step is the last step the player completed.
+	Step      int       `json:"step"`
+	UpdatedAt time.Time `json:"updated_at"`
 }
@@ -4,8 +4,6 @@ import (
 	"encoding/json"
 	"net/http"
 	"time"
-
-	"github.com/MultiX0/solo_leveling_system/db"
 )
 
 func WriteJsonResponse(w http.ResponseWriter, statusCode int, v any) error {
@@ -21,23 +19,40 @@ func WriteError(w http.ResponseWriter, statusCode int, err error) error {
 	return WriteJsonResponse(w, statusCode, map[string]any{"error": err.Error()})
 }
 
-func InsertToDB(table string, data any) ([]byte, error) {
-	newData, _, err := db.SupabaseClient.From(table).Insert(data, false, "", "", "exact").Single().Execute()
+func NowDate() string {
+	now := time.Now().UTC().Format("2006-01-02T15:04:05.999999Z")
+	return now
+}
 
-	if err != nil {
-		return nil, err
+// TimeLeft is the time left in the day t falls on, for days that start at
+// hour o'clock in loc.
+func TimeLeft(t time.Time, loc *time.Location, hour int) string {
+	return TimeUntil(NextReset(t, loc, hour))
+}
+
+// DayStart is when the day t falls on started, for days that start at
+// hour o'clock in loc.
+func DayStart(t time.Time, loc *time.Location, hour int) time.Time {
+	local := t.In(loc)
+	year, month, day := local.Date()
+
+	start := time.Date(year, month, day, hour, 0, 0, 0, loc)
+	if start.After(t) {
+		start = time.Date(year, month, day-1, hour, 0, 0, 0, loc)
 	}
 
-	return newData, nil
+	return start
 }
 
-func NowDate() string {
-	now := time.Now().UTC().Format("2006-01-02T15:04:05.999999Z")
-	return now
+// NextReset is when the day after the one t falls on starts.
+func NextReset(t time.Time, loc *time.Location, hour int) time.Time {
+	year, month, day := DayStart(t, loc, hour).Date()
+	return time.Date(year, month, day+1, hour, 0, 0, 0, loc)
 }
 
-func TimeLeft(t time.Time) string {
-	timeLeft := time.Until(t.Add(24 * time.Hour))
+// TimeUntil is the time left until t, rounded to the minute.
+func TimeUntil(t time.Time) string {
+	timeLeft := time.Until(t)
 
 	if timeLeft < 0 {
 		timeLeft = 0